@@ -0,0 +1,47 @@
+package neuro
+
+import (
+	"github.com/gonum/matrix/mat64"
+)
+
+// accumulate adds the stored gradients to the accumulation buffers and reports
+// whether enough steps were summed for a weights update. When it returns true
+// the gradients hold the accumulated sum, like the gradients of one larger batch.
+func (n *Network) accumulate() bool {
+	for i := range n.Layers {
+		l := &n.Layers[i]
+		if l.AccumWeights == nil {
+			r, c := l.DeltaWeights.Dims()
+			l.AccumWeights = mat64.NewDense(r, c, nil)
+			l.AccumBias = mat64.NewVector(l.NodesCount, nil)
+		}
+		l.AccumWeights.Add(l.AccumWeights, l.DeltaWeights)
+		l.AccumBias.AddVec(l.AccumBias, l.DeltaBias)
+	}
+	n.accumCount++
+	if n.accumCount < n.AccumSteps {
+		return false
+	}
+	for i := range n.Layers {
+		l := &n.Layers[i]
+		l.DeltaWeights.Copy(l.AccumWeights)
+		l.DeltaBias.CopyVec(l.AccumBias)
+		zeroDense(l.AccumWeights)
+		for k := 0; k < l.AccumBias.Len(); k++ {
+			l.AccumBias.SetVec(k, 0)
+		}
+	}
+	n.accumCount = 0
+	return true
+}
+
+// Sets all the values of the matrice to zero
+func zeroDense(m *mat64.Dense) {
+	r, _ := m.Dims()
+	for i := 0; i < r; i++ {
+		row := m.RawRowView(i)
+		for k := range row {
+			row[k] = 0
+		}
+	}
+}
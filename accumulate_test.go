@@ -0,0 +1,81 @@
+package neuro
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGradientAccumulation(t *testing.T) {
+	data := NetData{
+		Nodes:       []int{3, 4, 2},
+		Activations: []string{"tanh", "sigmoid"},
+		BatchSize:   3,
+		Train:       true,
+	}
+	a, err := New(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b trains on one batch holding the rows of both accumulated batches
+	data.WeightsData = exportWeights(t, a)
+	data.BatchSize = 6
+	b, err := New(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := [][]float64{{1, 1, 0}, {0, 1, 1}, {1, 0, 1}, {0.5, 0, 0}, {0, 0.2, 1}, {1, 1, 1}}
+	target := [][]float64{{1, 0}, {0, 1}, {0, 1}, {1, 0}, {1, 0}, {0, 1}}
+
+	a.LearnRate = 0.1
+	a.Momentum = 0.5
+	a.AccumSteps = 2
+	b.LearnRate = 0.1
+	b.Momentum = 0.5
+
+	before := exportWeights(t, a)
+	for step := 0; step < 2; step++ {
+		if err := a.Forward(in[step*3 : step*3+3]); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Backward(target[step*3 : step*3+3]); err != nil {
+			t.Fatal(err)
+		}
+		if step == 0 {
+			after := exportWeights(t, a)
+			for k := range before {
+				for i := range before[k].Weights {
+					if before[k].Weights[i] != after[k].Weights[i] {
+						t.Fatal("Weights changed before the accumulation finished")
+					}
+				}
+			}
+		}
+	}
+	if err := b.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Backward(target); err != nil {
+		t.Fatal(err)
+	}
+	wa, wb := exportWeights(t, a), exportWeights(t, b)
+	for k := range wa {
+		for i := range wa[k].Weights {
+			if math.Abs(wa[k].Weights[i]-wb[k].Weights[i]) > 1e-12 {
+				t.Errorf("Layer %d weight %d: got %v, want %v", k, i, wa[k].Weights[i], wb[k].Weights[i])
+			}
+		}
+		for i := range wa[k].BiasWeights {
+			if math.Abs(wa[k].BiasWeights[i]-wb[k].BiasWeights[i]) > 1e-12 {
+				t.Errorf("Layer %d bias %d: got %v, want %v", k, i, wa[k].BiasWeights[i], wb[k].BiasWeights[i])
+			}
+		}
+	}
+}
+
+func exportWeights(t *testing.T, n *Network) []DataWeights {
+	data, err := n.Export("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data.WeightsData
+}
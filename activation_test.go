@@ -0,0 +1,84 @@
+package neuro
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestActivateTranspose(t *testing.T) {
+	in := mat64.NewDense(2, 4, []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8})
+	acts := map[string]activationFunction{"softmax groups": &softmaxFunc{groups: []int{1, 3}}}
+	for name, act := range activationMap {
+		acts[name] = act
+	}
+	for name, act := range acts {
+		for _, deriv := range []bool{false, true} {
+			want := mat64.NewDense(2, 4, nil)
+			if err := act.activate(in, want, deriv, false); err != nil {
+				t.Fatal(err)
+			}
+			got := mat64.NewDense(4, 2, nil)
+			if err := act.activate(in, got, deriv, true); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 2; i++ {
+				for k := 0; k < 4; k++ {
+					if math.Abs(want.At(i, k)-got.At(k, i)) > 1e-15 {
+						t.Errorf("%s deriv %v: transposed value (%d, %d) is %v, want %v", name, deriv, k, i, got.At(k, i), want.At(i, k))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestForwardBackwardAllocs(t *testing.T) {
+	n := testNetwork(t, NetData{Train: true})
+	n.Momentum = 0.5
+	in, target := testIn, testTarget
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := n.Forward(in); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Forward allocates %v times per call", allocs)
+	}
+	allocs = testing.AllocsPerRun(100, func() {
+		if err := n.Backward(target); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Backward allocates %v times per call", allocs)
+	}
+}
+
+func BenchmarkForwardAllocs(b *testing.B) {
+	n, in := testNetwork(b, NetData{Train: true}), testIn
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := n.Forward(in); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBackwardAllocs(b *testing.B) {
+	n := testNetwork(b, NetData{Train: true})
+	n.Momentum = 0.5
+	in, target := testIn, testTarget
+	if err := n.Forward(in); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := n.Backward(target); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
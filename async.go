@@ -0,0 +1,183 @@
+package neuro
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// AsyncTrainer trains a network Hogwild style. The workers run Forward and
+// Backward on their own batches against shared weights without any locking:
+// every worker reads a snapshot of the shared weights and adds its update to
+// them element by element with atomic operations, so the updates of the other
+// workers can interleave. Momentum and gradient accumulation are not applied,
+// the clipping settings of the network are.
+type AsyncTrainer struct {
+	Net     *Network
+	workers []*Network
+	weights [][]uint64
+	bias    [][]uint64
+}
+
+// NewAsyncTrainer returns an asynchronous trainer for the network using the given number of workers.
+// With workers <= 0 GOMAXPROCS workers are used
+func NewAsyncTrainer(n *Network, workers int) (*AsyncTrainer, error) {
+	if !n.isTrain {
+		return nil, ErrNotTrainable
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	t := &AsyncTrainer{
+		Net:     n,
+		workers: make([]*Network, workers),
+		weights: make([][]uint64, len(n.Layers)),
+		bias:    make([][]uint64, len(n.Layers)),
+	}
+	nodes := make([]int, len(n.Layers)+1)
+	nodes[0] = n.InputCount
+	for k := range n.Layers {
+		nodes[k+1] = n.Layers[k].NodesCount
+		r, c := n.Layers[k].Weights.Dims()
+		t.weights[k] = make([]uint64, r*c)
+		t.bias[k] = make([]uint64, n.Layers[k].NodesCount)
+	}
+	for w := range t.workers {
+		r, err := New(NetData{
+			Nodes:         nodes,
+			Activations:   n.Activations,
+			BatchSize:     n.BatchSize,
+			Train:         true,
+			SoftmaxGroups: n.SoftmaxGroups,
+			Heads:         n.Heads,
+			Normalization: n.Normalization,
+			Preprocessing: n.Preprocessing,
+		})
+		if err != nil {
+			return nil, err
+		}
+		t.workers[w] = r
+	}
+	return t, nil
+}
+
+// Train runs Forward and Backward on every batch of inputs and targets,
+// spreading the batches over the workers, and stores the final weights in the network
+func (t *AsyncTrainer) Train(in, target [][][]float64) error {
+	n := t.Net
+	if len(in) != len(target) {
+		return ErrWrongBatchCount
+	}
+	if n.LearnRate <= 0.0 {
+		return ErrLearnRate
+	}
+	for k := range n.Layers {
+		c := n.Layers[k].NodesCount
+		r, _ := n.Layers[k].Weights.Dims()
+		for i := 0; i < r; i++ {
+			for j, v := range n.Layers[k].Weights.RawRowView(i) {
+				t.weights[k][i*c+j] = math.Float64bits(v)
+			}
+		}
+		for i := range t.bias[k] {
+			t.bias[k][i] = math.Float64bits(n.Layers[k].BiasWeights.At(i, 0))
+		}
+	}
+	batches := make(chan int)
+	errs := make([]error, len(t.workers))
+	// Only the batches trained without an error count as steps
+	var steps int64
+	var wg sync.WaitGroup
+	for w := range t.workers {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for b := range batches {
+				if errs[w] != nil {
+					continue
+				}
+				errs[w] = t.step(t.workers[w], in[b], target[b])
+				if errs[w] == nil {
+					atomic.AddInt64(&steps, 1)
+				}
+			}
+		}(w)
+	}
+	for b := range in {
+		batches <- b
+	}
+	close(batches)
+	wg.Wait()
+	for k := range n.Layers {
+		c := n.Layers[k].NodesCount
+		r, _ := n.Layers[k].Weights.Dims()
+		for i := 0; i < r; i++ {
+			row := n.Layers[k].Weights.RawRowView(i)
+			for j := range row {
+				row[j] = math.Float64frombits(t.weights[k][i*c+j])
+			}
+		}
+		for i := range t.bias[k] {
+			n.Layers[k].BiasWeights.SetVec(i, math.Float64frombits(t.bias[k][i]))
+		}
+	}
+	n.Step += int(steps)
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// step trains the worker on one batch and adds its update to the shared weights
+func (t *AsyncTrainer) step(w *Network, in, target [][]float64) error {
+	if len(in) > w.BatchSize || len(target) > w.BatchSize {
+		return ErrWrongBatchCount
+	}
+	w.Strict = t.Net.Strict
+	// Take a snapshot of the shared weights
+	for k := range w.Layers {
+		c := w.Layers[k].NodesCount
+		r, _ := w.Layers[k].Weights.Dims()
+		for i := 0; i < r; i++ {
+			row := w.Layers[k].Weights.RawRowView(i)
+			for j := range row {
+				row[j] = math.Float64frombits(atomic.LoadUint64(&t.weights[k][i*c+j]))
+			}
+		}
+		for i := range t.bias[k] {
+			w.Layers[k].BiasWeights.SetVec(i, math.Float64frombits(atomic.LoadUint64(&t.bias[k][i])))
+		}
+	}
+	if err := w.Forward(in); err != nil {
+		return err
+	}
+	if err := w.gradients(target); err != nil {
+		return err
+	}
+	w.ClipValue, w.ClipNorm = t.Net.ClipValue, t.Net.ClipNorm
+	w.GradNorm = w.clipGradients()
+	// The gradients are stored transposed to the weights
+	for k := range w.Layers {
+		c := w.Layers[k].NodesCount
+		for i := 0; i < c; i++ {
+			for j, v := range w.Layers[k].DeltaWeights.RawRowView(i) {
+				atomicAddFloat(&t.weights[k][j*c+i], t.Net.LearnRate*v)
+			}
+			atomicAddFloat(&t.bias[k][i], w.Layers[k].DeltaBias.At(i, 0))
+		}
+	}
+	return nil
+}
+
+// Adds delta to the float64 stored as bits in addr without locking
+func atomicAddFloat(addr *uint64, delta float64) {
+	for {
+		old := atomic.LoadUint64(addr)
+		if atomic.CompareAndSwapUint64(addr, old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package neuro
+
+import (
+	"testing"
+)
+
+var (
+	asyncIn = [][]float64{
+		[]float64{1, 1, 0}, []float64{0, 1, 1}, []float64{1, 0, 1},
+		[]float64{0, 0, 1}, []float64{1, 1, 1}, []float64{0, 1, 0},
+	}
+	asyncTarget = [][]float64{
+		[]float64{1, 0}, []float64{0, 1}, []float64{0, 1},
+		[]float64{1, 0}, []float64{1, 0}, []float64{0, 1},
+	}
+	asyncData = NetData{
+		Nodes:       []int{3, 16, 2},
+		Activations: []string{"tanh", "sigmoid"},
+		BatchSize:   6,
+		Train:       true,
+	}
+)
+
+func asyncBatches(count int) ([][][]float64, [][][]float64) {
+	in := make([][][]float64, count)
+	target := make([][][]float64, count)
+	for k := range in {
+		in[k] = asyncIn
+		target[k] = asyncTarget
+	}
+	return in, target
+}
+
+func TestAsyncTrainer(t *testing.T) {
+	n := testNetwork(t, asyncData)
+	if err := n.Forward(asyncIn); err != nil {
+		t.Fatal(err)
+	}
+	before, err := n.NetError(asyncTarget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trainer, err := NewAsyncTrainer(n, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, target := asyncBatches(200)
+	if err := trainer.Train(in, target); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Forward(asyncIn); err != nil {
+		t.Fatal(err)
+	}
+	after, err := n.NetError(asyncTarget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after >= before {
+		t.Errorf("Asynchronous training did not reduce the error: before %v, after %v", before, after)
+	}
+	if n.Step != 200 {
+		t.Errorf("Step is %d after 200 batches", n.Step)
+	}
+}
+
+func TestAsyncTrainerErrors(t *testing.T) {
+	n := testNetwork(t, asyncData)
+	n.Normalization = &Normalization{Mean: []float64{1, 1, 1}, Std: []float64{2, 2, 2}}
+	trainer, err := NewAsyncTrainer(n, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trainer.workers[0].Normalization == nil {
+		t.Error("The workers do not normalize the inputs")
+	}
+	in, target := asyncBatches(3)
+	in[1] = [][]float64{[]float64{1, 1}}
+	if err := trainer.Train(in, target); err == nil {
+		t.Error("Expected an error for rows of the wrong width")
+	}
+	// The single worker stops at its first error
+	if n.Step != 1 {
+		t.Errorf("Step is %d, want 1 successful step", n.Step)
+	}
+}
+
+func BenchmarkAsyncTrainer(b *testing.B) {
+	n := testNetwork(b, asyncData)
+	trainer, err := NewAsyncTrainer(n, 4)
+	if err != nil {
+		b.Fatal(err)
+	}
+	in, target := asyncBatches(4)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := trainer.Train(in, target); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParallelTrainer(b *testing.B) {
+	n := testNetwork(b, asyncData)
+	trainer, err := NewParallelTrainer(n, 4)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Same number of batches as an AsyncTrainer iteration
+		for k := 0; k < 4; k++ {
+			if err := trainer.Step(asyncIn, asyncTarget); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package neuro
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+var benchSizes = []struct {
+	name  string
+	nodes []int
+}{
+	{"small", []int{3, 10, 5, 2}},
+	{"medium", []int{32, 64, 32, 10}},
+	{"large", []int{256, 512, 256, 10}},
+}
+
+var benchBatchSizes = []int{1, 16, 64}
+
+func benchNetData(nodes []int, batchSize int) NetData {
+	return NetData{
+		Nodes:        nodes,
+		Activations:  []string{"tanh", "sigmoid", "softmax"},
+		BatchSize:    batchSize,
+		Train:        true,
+		SplitSoftmax: nodes[len(nodes)-1],
+	}
+}
+
+func benchData(n *Network) ([][]float64, [][]float64) {
+	in := randomRows(n, n.BatchSize)
+	target := make([][]float64, n.BatchSize)
+	for k := range target {
+		target[k] = make([]float64, n.Layers[n.OutputLayer].NodesCount)
+		target[k][k%len(target[k])] = 1
+	}
+	return in, target
+}
+
+// runBenchmarks runs f for every network size and batch size
+func runBenchmarks(b *testing.B, f func(b *testing.B, data NetData)) {
+	for _, size := range benchSizes {
+		for _, batchSize := range benchBatchSizes {
+			data := benchNetData(size.nodes, batchSize)
+			b.Run(fmt.Sprintf("%s/batch%d", size.name, batchSize), func(b *testing.B) {
+				b.ReportAllocs()
+				f(b, data)
+			})
+		}
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	runBenchmarks(b, func(b *testing.B, data NetData) {
+		for i := 0; i < b.N; i++ {
+			if _, err := New(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkForward(b *testing.B) {
+	runBenchmarks(b, func(b *testing.B, data NetData) {
+		n, err := New(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		in, _ := benchData(n)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := n.Forward(in); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkBackward(b *testing.B) {
+	runBenchmarks(b, func(b *testing.B, data NetData) {
+		n, err := New(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		n.LearnRate = 0.01
+		n.Momentum = 0.5
+		in, target := benchData(n)
+		if err := n.Forward(in); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := n.Backward(target); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkExport(b *testing.B) {
+	runBenchmarks(b, func(b *testing.B, data NetData) {
+		n, err := New(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		path := filepath.Join(b.TempDir(), "network.json")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := n.Export(path); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkImport(b *testing.B) {
+	runBenchmarks(b, func(b *testing.B, data NetData) {
+		n, err := New(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		path := filepath.Join(b.TempDir(), "network.json")
+		if _, err := n.Export(path); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := Import(path, data.BatchSize, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,237 @@
+package neuro
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+)
+
+// Binary model layout, all the numbers are little-endian:
+//
+//	magic       [4]byte "NRNB"
+//	version     uint16
+//	value size  uint8, 4 for float32 or 8 for float64 values
+//	reserved    uint8
+//	header size uint32
+//	header      JSON Model without the weights
+//	tensors     weights then bias weights of every layer, each one
+//	            as rows uint32, cols uint32 and rows*cols values
+//
+// The whole stream can be gzip compressed.
+type binaryHeader struct {
+	Magic      [4]byte
+	Version    uint16
+	ValueSize  uint8
+	Reserved   uint8
+	HeaderSize uint32
+}
+
+const (
+	binaryVersion   = 1
+	maxBinaryHeader = 64 << 20
+)
+
+var binaryMagic = [4]byte{'N', 'R', 'N', 'B'}
+
+// WriteTo writes the network as a binary model. The values are stored
+// with the network's Precision and the stream is gzipped if Compress is set
+func (n *Network) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	out := io.Writer(cw)
+	var zw *gzip.Writer
+	if n.Compress {
+		zw = gzip.NewWriter(cw)
+		out = zw
+	}
+	bw := bufio.NewWriter(out)
+	if err := n.writeBinary(bw); err != nil {
+		return cw.n, err
+	}
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+// ReadFrom replaces the network with a binary model read from r,
+// the stored batch size and training mode are used. An uncompressed model
+// is read exactly and r is left after its last byte, a gzipped model is
+// read through a buffer that can consume the bytes following it
+func (n *Network) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countReader{r: r}
+	model, err := readBinary(cr)
+	if err != nil {
+		return cr.n, err
+	}
+	y, err := New(model.NetData)
+	if err != nil {
+		return cr.n, err
+	}
+	model.restore(y)
+	*n = *y
+	return cr.n, nil
+}
+
+func (n *Network) writeBinary(w io.Writer) error {
+	data, err := n.Export("")
+	if err != nil {
+		return err
+	}
+	weights := widenWeights(data.WeightsData)
+	data.WeightsData = nil
+	js, err := json.Marshal(n.model(data))
+	if err != nil {
+		return err
+	}
+	header := binaryHeader{
+		Magic:      binaryMagic,
+		Version:    binaryVersion,
+		ValueSize:  8,
+		HeaderSize: uint32(len(js)),
+	}
+	if n.Precision == "float32" {
+		header.ValueSize = 4
+	}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	if _, err := w.Write(js); err != nil {
+		return err
+	}
+	for k := range weights {
+		if err := writeTensor(w, data.Nodes[k], data.Nodes[k+1], weights[k].Weights, header.ValueSize); err != nil {
+			return err
+		}
+		if err := writeTensor(w, 1, data.Nodes[k+1], weights[k].BiasWeights, header.ValueSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBinary reads a binary model, gzipped or not, with its weights.
+// Uncompressed models are read without buffering, so r is left right after the model
+func readBinary(r io.Reader) (Model, error) {
+	var magic [2]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return Model{}, err
+	}
+	in := io.MultiReader(bytes.NewReader(magic[:]), r)
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			return Model{}, err
+		}
+		defer zr.Close()
+		in = zr
+	}
+	header := binaryHeader{}
+	if err := binary.Read(in, binary.LittleEndian, &header); err != nil {
+		return Model{}, err
+	}
+	if header.Magic != binaryMagic {
+		return Model{}, ErrBinaryFormat
+	}
+	if header.Version > binaryVersion {
+		return Model{}, ErrFormatVersion
+	}
+	if (header.ValueSize != 4 && header.ValueSize != 8) || header.HeaderSize > maxBinaryHeader {
+		return Model{}, ErrBinaryFormat
+	}
+	js := make([]byte, header.HeaderSize)
+	if _, err := io.ReadFull(in, js); err != nil {
+		return Model{}, err
+	}
+	model := Model{}
+	if err := json.Unmarshal(js, &model); err != nil {
+		return Model{}, err
+	}
+	if err := model.validate(); err != nil {
+		return Model{}, err
+	}
+	model.WeightsData = make([]DataWeights, len(model.Nodes)-1)
+	var err error
+	for k := range model.WeightsData {
+		model.WeightsData[k].Weights, err = readTensor(in, model.Nodes[k], model.Nodes[k+1], header.ValueSize)
+		if err != nil {
+			return Model{}, err
+		}
+		model.WeightsData[k].BiasWeights, err = readTensor(in, 1, model.Nodes[k+1], header.ValueSize)
+		if err != nil {
+			return Model{}, err
+		}
+	}
+	return model, nil
+}
+
+func writeTensor(w io.Writer, rows, cols int, values []float64, valueSize uint8) error {
+	if err := binary.Write(w, binary.LittleEndian, [2]uint32{uint32(rows), uint32(cols)}); err != nil {
+		return err
+	}
+	if valueSize == 8 {
+		return binary.Write(w, binary.LittleEndian, values)
+	}
+	values32 := make([]float32, len(values))
+	for k, v := range values {
+		values32[k] = float32(v)
+	}
+	return binary.Write(w, binary.LittleEndian, values32)
+}
+
+// readTensor reads a tensor and checks it has the expected shape
+func readTensor(r io.Reader, rows, cols int, valueSize uint8) ([]float64, error) {
+	var dims [2]uint32
+	if err := binary.Read(r, binary.LittleEndian, &dims); err != nil {
+		return nil, err
+	}
+	if int64(dims[0]) != int64(rows) || int64(dims[1]) != int64(cols) {
+		return nil, ErrWeightMismatch
+	}
+	if uint64(dims[0])*uint64(dims[1]) > maxReadValues {
+		return nil, ErrBinaryFormat
+	}
+	if valueSize == 8 {
+		return readChunked(r, rows*cols, 8, func(b []byte) float64 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		})
+	}
+	return readChunked(r, rows*cols, 4, func(b []byte) float64 {
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	})
+}
+
+// isBinaryModel reports whether the file content is a binary model, gzipped or not
+func isBinaryModel(file []byte) bool {
+	return bytes.HasPrefix(file, binaryMagic[:]) || bytes.HasPrefix(file, []byte{0x1f, 0x8b})
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
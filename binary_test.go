@@ -0,0 +1,152 @@
+package neuro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func assertSameWeights(t *testing.T, want, got []DataWeights) {
+	for k := range want {
+		if len(want[k].Weights) != len(got[k].Weights) || len(want[k].BiasWeights) != len(got[k].BiasWeights) {
+			t.Fatalf("Layer %d shapes differ", k)
+		}
+		for i := range want[k].Weights {
+			if math.Float64bits(want[k].Weights[i]) != math.Float64bits(got[k].Weights[i]) {
+				t.Errorf("Layer %d weight %d: got %v, want %v", k, i, got[k].Weights[i], want[k].Weights[i])
+			}
+		}
+		for i := range want[k].BiasWeights {
+			if math.Float64bits(want[k].BiasWeights[i]) != math.Float64bits(got[k].BiasWeights[i]) {
+				t.Errorf("Layer %d bias %d: got %v, want %v", k, i, got[k].BiasWeights[i], want[k].BiasWeights[i])
+			}
+		}
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		n := testNetwork(t, NetData{Train: true})
+		n.Compress = compress
+		buf := &bytes.Buffer{}
+		written, err := n.WriteTo(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if written != int64(buf.Len()) {
+			t.Errorf("WriteTo reported %d bytes, wrote %d", written, buf.Len())
+		}
+		y := new(Network)
+		if _, err := y.ReadFrom(buf); err != nil {
+			t.Fatal(err)
+		}
+		if y.BatchSize != n.BatchSize || y.SplitSoftmax != n.SplitSoftmax || !y.isTrain {
+			t.Errorf("Compress %v: network settings were not restored", compress)
+		}
+		assertSameWeights(t, exportWeights(t, n), exportWeights(t, y))
+	}
+}
+
+func TestBinaryReadExact(t *testing.T) {
+	n := testNetwork(t, NetData{Train: true})
+	buf := &bytes.Buffer{}
+	written, err := n.WriteTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("trailer")
+	read, err := new(Network).ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != written || buf.String() != "trailer" {
+		t.Errorf("ReadFrom read %d bytes of %d and left %q", read, written, buf.String())
+	}
+}
+
+func TestBinaryTensorSize(t *testing.T) {
+	for _, nodes := range [][]int{{100000, 100000}, {4096, 4096}} {
+		js, err := json.Marshal(Model{
+			FormatVersion: FormatVersion,
+			Loss:          activationMap["sigmoid"].loss(),
+			NetData:       NetData{Nodes: nodes, Activations: []string{"sigmoid"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		header := binaryHeader{Magic: binaryMagic, Version: binaryVersion, ValueSize: 8, HeaderSize: uint32(len(js))}
+		if err := binary.Write(buf, binary.LittleEndian, header); err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(js)
+		binary.Write(buf, binary.LittleEndian, [2]uint32{uint32(nodes[0]), uint32(nodes[1])})
+		// The stream stops after a few values of the tensor
+		buf.Write(make([]byte, 64))
+		if _, err := new(Network).ReadFrom(buf); err == nil {
+			t.Errorf("Expected an error for a truncated %v tensor", nodes)
+		}
+	}
+}
+
+func TestBinaryFloat32(t *testing.T) {
+	n := testNetwork(t, NetData{Train: true})
+	n.Precision = "float32"
+	buf := &bytes.Buffer{}
+	if _, err := n.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	y := new(Network)
+	if _, err := y.ReadFrom(buf); err != nil {
+		t.Fatal(err)
+	}
+	assertSameWeights(t, widenWeights(exportWeights(t, n)), widenWeights(exportWeights(t, y)))
+}
+
+func TestBinarySize(t *testing.T) {
+	n := testNetwork(t, NetData{Train: true})
+	buf := &bytes.Buffer{}
+	if _, err := n.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	data, err := n.Export("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(n.model(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(js) {
+		t.Errorf("Binary model is not smaller than JSON: %d >= %d bytes", buf.Len(), len(js))
+	}
+}
+
+func TestExportImportBinary(t *testing.T) {
+	n := testNetwork(t, NetData{Train: true})
+	n.Format = "binary"
+	n.Compress = true
+	path := filepath.Join(t.TempDir(), "model.bin.gz")
+	if _, err := n.Export(path); err != nil {
+		t.Fatal(err)
+	}
+	y, err := Import(path, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y.BatchSize != 1 || y.isTrain {
+		t.Error("Import arguments were not applied")
+	}
+	assertSameWeights(t, exportWeights(t, n), exportWeights(t, y))
+
+	n.Format = "yaml"
+	if _, err := n.Export(path); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	if _, err := new(Network).ReadFrom(bytes.NewReader([]byte("NRNX0000000000"))); err == nil {
+		t.Error("Expected an error for an invalid binary model")
+	}
+}
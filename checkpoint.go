@@ -0,0 +1,167 @@
+package neuro
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"os"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+type (
+	// Checkpoint is the full training state of a network: the model at full
+	// precision plus the optimizer state, the counters and the random generator
+	// state needed to resume training exactly where it stopped
+	Checkpoint struct {
+		Model
+		AccumCount  int
+		Step        int
+		Epoch       int
+		RandState   uint64
+		LayersState []LayerState
+	}
+	// LayerState holds the optimizer buffers of a layer, stored row by row
+	LayerState struct {
+		DeltaWeightsPrev []float64 `json:",omitempty"`
+		AccumWeights     []float64 `json:",omitempty"`
+		AccumBias        []float64 `json:",omitempty"`
+	}
+)
+
+// Rand returns the network's random generator, its state is saved in checkpoints
+func (n *Network) Rand() *rand.Rand {
+	return n.rng
+}
+
+// SaveCheckpoint writes the training state of the network to a file, the
+// file is replaced atomically so a crash never leaves a partial checkpoint
+func (n *Network) SaveCheckpoint(path string) error {
+	return writeFileAtomic(path, n.EncodeCheckpoint)
+}
+
+// LoadCheckpoint reads a checkpoint file and returns the network ready to resume training
+func LoadCheckpoint(path string) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return DecodeCheckpoint(file)
+}
+
+// EncodeCheckpoint writes the training state of the network to w as JSON
+func (n *Network) EncodeCheckpoint(w io.Writer) error {
+	c := Checkpoint{
+		Model:       n.model(n.netData()),
+		AccumCount:  n.accumCount,
+		Step:        n.Step,
+		Epoch:       n.Epoch,
+		RandState:   n.source.state,
+		LayersState: make([]LayerState, len(n.Layers)),
+	}
+	for k := range n.Layers {
+		c.LayersState[k].DeltaWeightsPrev = denseData(n.Layers[k].DeltaWeightsPrev)
+		c.LayersState[k].AccumWeights = denseData(n.Layers[k].AccumWeights)
+		if n.Layers[k].AccumBias != nil {
+			c.LayersState[k].AccumBias = mat64.Col(nil, 0, n.Layers[k].AccumBias)
+		}
+	}
+	js, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(js)
+	return err
+}
+
+// DecodeCheckpoint reads a checkpoint from r and restores the network with its training state
+func DecodeCheckpoint(r io.Reader) (*Network, error) {
+	c := Checkpoint{}
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, err
+	}
+	if err := c.Model.validate(); err != nil {
+		return nil, err
+	}
+	n, err := New(c.NetData)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.LayersState) != len(n.Layers) {
+		return nil, ErrCheckpoint
+	}
+	for k := range n.Layers {
+		l := &n.Layers[k]
+		state := c.LayersState[k]
+		if state.DeltaWeightsPrev != nil {
+			if l.DeltaWeightsPrev == nil || !setDenseData(l.DeltaWeightsPrev, state.DeltaWeightsPrev) {
+				return nil, ErrCheckpoint
+			}
+		}
+		if state.AccumWeights != nil {
+			if l.DeltaWeights == nil || len(state.AccumBias) != l.NodesCount {
+				return nil, ErrCheckpoint
+			}
+			rows, cols := l.DeltaWeights.Dims()
+			l.AccumWeights = mat64.NewDense(rows, cols, nil)
+			if !setDenseData(l.AccumWeights, state.AccumWeights) {
+				return nil, ErrCheckpoint
+			}
+			l.AccumBias = mat64.NewVector(l.NodesCount, state.AccumBias)
+		}
+	}
+	c.Model.restore(n)
+	n.accumCount = c.AccumCount
+	n.Step = c.Step
+	n.Epoch = c.Epoch
+	n.source.state = c.RandState
+	return n, nil
+}
+
+// denseData copies the values of the matrice row by row, nil matrices give nil
+func denseData(m *mat64.Dense) []float64 {
+	if m == nil {
+		return nil
+	}
+	r, c := m.Dims()
+	data := make([]float64, 0, r*c)
+	for i := 0; i < r; i++ {
+		data = append(data, m.RawRowView(i)...)
+	}
+	return data
+}
+
+// setDenseData copies the values in to the matrice and reports whether their count matched
+func setDenseData(m *mat64.Dense, data []float64) bool {
+	r, c := m.Dims()
+	if len(data) != r*c {
+		return false
+	}
+	for i := 0; i < r; i++ {
+		copy(m.RawRowView(i), data[i*c:(i+1)*c])
+	}
+	return true
+}
+
+// rngSource is a splitmix64 random source whose whole state is a single
+// number, so the generator can be saved and restored with the network
+type rngSource struct {
+	state uint64
+}
+
+func (s *rngSource) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+func (s *rngSource) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *rngSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
@@ -0,0 +1,91 @@
+package neuro
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+// trainSteps runs Forward and Backward on random batches drawn from the network's generator
+func trainSteps(t *testing.T, n *Network, steps int) {
+	for s := 0; s < steps; s++ {
+		in := make([][]float64, n.BatchSize)
+		target := make([][]float64, n.BatchSize)
+		for k := range in {
+			in[k] = randomFunc(n.Rand(), 1, n.InputCount, -1, 1)
+			target[k] = make([]float64, 4)
+			target[k][n.Rand().Intn(4)] = 1
+		}
+		if err := n.Forward(in); err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Backward(target); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	n := testNetwork(t, NetData{Train: true, Seed: 7})
+	n.Momentum = 0.5
+	n.ClipNorm = 1
+	n.AccumSteps = 3
+	// Stop in the middle of an accumulation so the buffers are part of the state
+	trainSteps(t, n, 4)
+	n.Epoch = 2
+	buf := &bytes.Buffer{}
+	if err := n.EncodeCheckpoint(buf); err != nil {
+		t.Fatal(err)
+	}
+	y, err := DecodeCheckpoint(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y.Step != 4 || y.Epoch != 2 || y.LearnRate != n.LearnRate || y.AccumSteps != n.AccumSteps || !y.isTrain {
+		t.Fatal("Training settings were not restored")
+	}
+	trainSteps(t, n, 5)
+	trainSteps(t, y, 5)
+	assertSameWeights(t, exportWeights(t, n), exportWeights(t, y))
+	if n.Rand().Int63() != y.Rand().Int63() {
+		t.Error("Random generator state was not restored")
+	}
+}
+
+func TestCheckpointFile(t *testing.T) {
+	n := testNetwork(t, NetData{Train: true, Seed: 7})
+	n.Momentum = 0.5
+	n.ClipNorm = 1
+	n.AccumSteps = 3
+	n.Precision = "float32"
+	trainSteps(t, n, 3)
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	if err := n.SaveCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	y, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Checkpoints keep full precision whatever the export precision is
+	assertSameWeights(t, n.netData().WeightsData, y.netData().WeightsData)
+	if y.Precision != "float32" {
+		t.Error("Precision was not restored")
+	}
+	if _, err := LoadCheckpoint(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing checkpoint")
+	}
+}
+
+func TestSeed(t *testing.T) {
+	data := NetData{Nodes: []int{2, 3, 1}, Activations: []string{"sigmoid", "sigmoid"}, BatchSize: 1, Seed: 42}
+	a, err := New(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameWeights(t, exportWeights(t, a), exportWeights(t, b))
+}
@@ -0,0 +1,72 @@
+package neuro
+
+import (
+	"math"
+)
+
+// clipGradients limits the stored gradients by value and by global norm
+// and returns the global L2 norm measured before any clipping
+func (n *Network) clipGradients() float64 {
+	norm := n.gradientNorm()
+	if n.ClipValue > 0 {
+		for i := range n.Layers {
+			clipLayerValue(&n.Layers[i], n.ClipValue)
+		}
+	}
+	if n.ClipNorm <= 0 {
+		return norm
+	}
+	// The value clipping may have already shrunk the gradients
+	clipped := norm
+	if n.ClipValue > 0 {
+		clipped = n.gradientNorm()
+	}
+	if clipped > n.ClipNorm {
+		scale := n.ClipNorm / clipped
+		for i := range n.Layers {
+			for r := 0; r < n.Layers[i].NodesCount; r++ {
+				row := n.Layers[i].DeltaWeights.RawRowView(r)
+				for k := range row {
+					row[k] *= scale
+				}
+			}
+			n.Layers[i].DeltaBias.ScaleVec(scale, n.Layers[i].DeltaBias)
+		}
+	}
+	return norm
+}
+
+// gradientNorm returns the L2 norm of all the weight and bias gradients of the network
+func (n *Network) gradientNorm() float64 {
+	var sum float64
+	for i := range n.Layers {
+		r, _ := n.Layers[i].DeltaWeights.Dims()
+		for row := 0; row < r; row++ {
+			for _, v := range n.Layers[i].DeltaWeights.RawRowView(row) {
+				sum += v * v
+			}
+		}
+		for row := 0; row < n.Layers[i].DeltaBias.Len(); row++ {
+			v := n.Layers[i].DeltaBias.At(row, 0)
+			sum += v * v
+		}
+	}
+	return math.Sqrt(sum)
+}
+
+// clipLayerValue limits every gradient element of the layer to [-limit, limit]
+func clipLayerValue(l *Layer, limit float64) {
+	r, _ := l.DeltaWeights.Dims()
+	for row := 0; row < r; row++ {
+		clipFloat(l.DeltaWeights.RawRowView(row), limit)
+	}
+	for row := 0; row < l.DeltaBias.Len(); row++ {
+		l.DeltaBias.SetVec(row, math.Max(-limit, math.Min(limit, l.DeltaBias.At(row, 0))))
+	}
+}
+
+func clipFloat(a []float64, limit float64) {
+	for k := range a {
+		a[k] = math.Max(-limit, math.Min(limit, a[k]))
+	}
+}
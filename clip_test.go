@@ -0,0 +1,79 @@
+package neuro
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClipValue(t *testing.T) {
+	n := testNetwork(t, NetData{Train: true})
+	if err := n.Forward(testIn); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.gradients(testTarget); err != nil {
+		t.Fatal(err)
+	}
+	want := n.gradientNorm()
+	n.ClipValue = 0.01
+	if got := n.clipGradients(); got != want {
+		t.Errorf("Pre-clip norm: got %v, want %v", got, want)
+	}
+	for k := range n.Layers {
+		r, _ := n.Layers[k].DeltaWeights.Dims()
+		for i := 0; i < r; i++ {
+			for _, v := range n.Layers[k].DeltaWeights.RawRowView(i) {
+				if math.Abs(v) > n.ClipValue {
+					t.Errorf("Layer %d weight gradient %v exceeds %v", k, v, n.ClipValue)
+				}
+			}
+		}
+		for i := 0; i < n.Layers[k].DeltaBias.Len(); i++ {
+			if v := n.Layers[k].DeltaBias.At(i, 0); math.Abs(v) > n.ClipValue {
+				t.Errorf("Layer %d bias gradient %v exceeds %v", k, v, n.ClipValue)
+			}
+		}
+	}
+}
+
+func TestClipNorm(t *testing.T) {
+	n := testNetwork(t, NetData{Train: true})
+	if err := n.Forward(testIn); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.gradients(testTarget); err != nil {
+		t.Fatal(err)
+	}
+	want := n.gradientNorm()
+	n.ClipNorm = want / 2
+	if got := n.clipGradients(); got != want {
+		t.Errorf("Pre-clip norm: got %v, want %v", got, want)
+	}
+	if got := n.gradientNorm(); math.Abs(got-n.ClipNorm) > 1e-12 {
+		t.Errorf("Clipped norm: got %v, want %v", got, n.ClipNorm)
+	}
+	// Gradients below the threshold are left untouched
+	n.ClipNorm = want
+	before := n.gradientNorm()
+	n.clipGradients()
+	if got := n.gradientNorm(); got != before {
+		t.Errorf("Norm changed below threshold: got %v, want %v", got, before)
+	}
+}
+
+func TestBackwardGradNorm(t *testing.T) {
+	n := testNetwork(t, NetData{Train: true})
+	in, target := testIn, testTarget
+	n.ClipNorm = 1e-3
+	if err := n.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Backward(target); err != nil {
+		t.Fatal(err)
+	}
+	if n.GradNorm <= 0 || math.IsNaN(n.GradNorm) {
+		t.Errorf("Expected a positive gradient norm, got %v", n.GradNorm)
+	}
+	if got := n.gradientNorm(); got > n.ClipNorm+1e-12 {
+		t.Errorf("Applied update norm %v is not clipped", got)
+	}
+}
@@ -0,0 +1,132 @@
+// Command benchcheck compares `go test -bench` output against a stored
+// baseline and reports the benchmarks that got slower or allocate more.
+//
+//	go test -run NONE -bench . -benchmem -count 5 > bench_output.txt
+//	go run ./cmd/benchcheck -baseline testdata/bench_baseline.txt bench_output.txt
+//
+// When a benchmark was run several times the fastest run is used. The exit
+// status is 1 when a regression was found.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type result struct {
+	NsPerOp     float64
+	AllocsPerOp float64
+	HasAllocs   bool
+}
+
+var procsSuffix = regexp.MustCompile(`-\d+$`)
+
+func main() {
+	baseline := flag.String("baseline", "testdata/bench_baseline.txt", "stored benchmark output to compare against")
+	threshold := flag.Float64("threshold", 10, "allowed slowdown in percent before a benchmark is flagged")
+	flag.Parse()
+
+	base, err := parseFile(*baseline)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var current map[string]result
+	switch flag.NArg() {
+	case 0:
+		current, err = parse(os.Stdin)
+	case 1:
+		current, err = parseFile(flag.Arg(0))
+	default:
+		log.Fatal("usage: benchcheck [-baseline file] [-threshold percent] [bench output]")
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	regressions := 0
+	for _, name := range names {
+		cur := current[name]
+		old, ok := base[name]
+		if !ok {
+			fmt.Printf("%-50s %14s %12.0f ns/op  new\n", name, "", cur.NsPerOp)
+			continue
+		}
+		delta := (cur.NsPerOp - old.NsPerOp) / old.NsPerOp * 100
+		status := "ok"
+		if delta > *threshold {
+			status = "REGRESSION"
+		}
+		if old.HasAllocs && cur.HasAllocs && cur.AllocsPerOp > old.AllocsPerOp {
+			status = fmt.Sprintf("REGRESSION allocs %.0f -> %.0f", old.AllocsPerOp, cur.AllocsPerOp)
+		}
+		if status != "ok" {
+			regressions++
+		}
+		fmt.Printf("%-50s %8.0f ns/op %8.0f ns/op %+7.1f%%  %s\n", name, old.NsPerOp, cur.NsPerOp, delta, status)
+	}
+	if regressions > 0 {
+		fmt.Printf("%d regression(s) found\n", regressions)
+		os.Exit(1)
+	}
+}
+
+func parseFile(path string) (map[string]result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+// parse reads benchmark lines and keeps the fastest run of every benchmark
+func parse(r io.Reader) (map[string]result, error) {
+	results := map[string]result{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		name := procsSuffix.ReplaceAllString(fields[0], "")
+		var res result
+		found := false
+		// Values come in pairs after the iteration count: 123 ns/op 45 B/op 6 allocs/op
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				res.NsPerOp = v
+				found = true
+			case "allocs/op":
+				res.AllocsPerOp = v
+				res.HasAllocs = true
+			}
+		}
+		if !found {
+			continue
+		}
+		if old, ok := results[name]; ok && old.NsPerOp <= res.NsPerOp {
+			continue
+		}
+		results[name] = res
+	}
+	return results, scanner.Err()
+}
@@ -0,0 +1,117 @@
+// Command mnist trains a classifier on the MNIST digits, or any other IDX
+// images and labels, and reports its accuracy on the test files.
+//
+//	go run ./cmd/mnist -train-images train-images-idx3-ubyte.gz -train-labels train-labels-idx1-ubyte.gz \
+//		-test-images t10k-images-idx3-ubyte.gz -test-labels t10k-labels-idx1-ubyte.gz
+//
+// Without test files the accuracy is measured on the training files.
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/ingn/neuro"
+)
+
+func main() {
+	trainImages := flag.String("train-images", "testdata/mnist/images-idx3-ubyte", "IDX file of the training images")
+	trainLabels := flag.String("train-labels", "testdata/mnist/labels-idx1-ubyte", "IDX file of the training labels")
+	testImages := flag.String("test-images", "", "IDX file of the test images")
+	testLabels := flag.String("test-labels", "", "IDX file of the test labels")
+	classes := flag.Int("classes", 10, "number of classes, 0 counts them from the labels")
+	hidden := flag.Int("hidden", 128, "nodes of the hidden layer")
+	batchSize := flag.Int("batch", 32, "samples of every training batch")
+	epochs := flag.Int("epochs", 5, "passes over the training samples")
+	learnRate := flag.Float64("rate", 0.01, "learn rate")
+	output := flag.String("o", "", "file to export the trained model to")
+	flag.Parse()
+
+	train, err := neuro.LoadIDX(*trainImages, *trainLabels, *classes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if train.Len() == 0 {
+		log.Fatalf("%s holds no images", *trainImages)
+	}
+	test := train
+	if *testImages != "" {
+		if test, err = neuro.LoadIDX(*testImages, *testLabels, len(train.Targets[0])); err != nil {
+			log.Fatal(err)
+		}
+		if test.Len() == 0 {
+			log.Fatalf("%s holds no images", *testImages)
+		}
+	}
+	n, err := neuro.New(neuro.NetData{
+		Nodes:       []int{len(train.Inputs[0]), *hidden, len(train.Targets[0])},
+		Activations: []string{"tanh", "softmax"},
+		BatchSize:   *batchSize,
+		Train:       true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	n.LearnRate = *learnRate
+	n.Momentum = 0.5
+
+	for epoch := 0; epoch < *epochs; epoch++ {
+		start := time.Now()
+		train.Shuffle(n.Rand())
+		for b := 0; b < train.Len(); b += *batchSize {
+			in, target := train.Batch(b, batchEnd(b, *batchSize, train.Len()))
+			if err := n.Forward(in); err != nil {
+				log.Fatal(err)
+			}
+			if err := n.Backward(target); err != nil {
+				log.Fatal(err)
+			}
+		}
+		n.Epoch++
+		accuracy, err := evaluate(n, test, *batchSize)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("epoch %d: accuracy %.2f%% in %s", n.Epoch, 100*accuracy, time.Since(start))
+	}
+	if *output != "" {
+		if _, err := n.Export(*output); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// evaluate returns the share of the samples whose largest output is their class
+func evaluate(n *neuro.Network, d *neuro.MemoryDataset, batchSize int) (float64, error) {
+	correct := 0
+	for b := 0; b < d.Len(); b += batchSize {
+		in, target := d.Batch(b, batchEnd(b, batchSize, d.Len()))
+		if err := n.Forward(in); err != nil {
+			return 0, err
+		}
+		for k, row := range n.GetOutput()[:len(in)] {
+			if target[k][argmax(row)] == 1 {
+				correct++
+			}
+		}
+	}
+	return float64(correct) / float64(d.Len()), nil
+}
+
+func batchEnd(start, batchSize, count int) int {
+	if start+batchSize > count {
+		return count
+	}
+	return start + batchSize
+}
+
+func argmax(row []float64) int {
+	best := 0
+	for k, v := range row {
+		if v > row[best] {
+			best = k
+		}
+	}
+	return best
+}
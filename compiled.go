@@ -0,0 +1,194 @@
+package neuro
+
+type (
+	// Compiled is an inference engine built from a network. The weights of
+	// every layer are flattened in to contiguous node-major slices and the
+	// matrix product, the bias and the activation are fused in one pass per
+	// row, so Predict does not depend on mat64 and does not allocate unless
+	// the network has a Preprocessing pipeline.
+	Compiled struct {
+		Activations []string
+		InputCount  int
+		BatchSize   int
+		// preprocessing and normalization are applied to the input rows, like in Network.Forward
+		preprocessing Pipeline
+		normalization *Normalization
+		layers        []compiledLayer
+		input         []float64
+		output        [][]float64
+	}
+	compiledLayer struct {
+		weights    []float64
+		bias       []float64
+		nodes      []float64
+		nodesCount int
+		inputCount int
+		activate   func([]float64)
+	}
+)
+
+// Float64 activation functions applied in place on a row, registered by the activation files
+var activationRowMap = map[string]func([]float64){}
+
+// rowActivator is implemented by the activation functions configured per
+// layer, like softmax with its groups, instead of an activationRowMap entry
+type rowActivator interface {
+	activateRow([]float64)
+}
+
+// Compile returns an inference engine with a copy of the network weights
+func (n *Network) Compile() (*Compiled, error) {
+	c := &Compiled{
+		Activations:   n.Activations,
+		InputCount:    n.InputCount,
+		BatchSize:     n.BatchSize,
+		preprocessing: n.Preprocessing.clone(),
+		normalization: n.Normalization.clone(),
+		layers:        make([]compiledLayer, len(n.Layers)),
+		input:         make([]float64, n.BatchSize*n.InputCount),
+		output:        make([][]float64, n.BatchSize),
+	}
+	for k := range n.Layers {
+		act, ok := activationRowMap[n.Activations[k]]
+		if r, isRow := n.Layers[k].Activation.(rowActivator); isRow {
+			act, ok = r.activateRow, true
+		}
+		if !ok {
+			return nil, ErrUnknownActivation
+		}
+		r, cols := n.Layers[k].Weights.Dims()
+		l := compiledLayer{
+			weights:    make([]float64, r*cols),
+			bias:       make([]float64, cols),
+			nodes:      make([]float64, n.BatchSize*cols),
+			nodesCount: cols,
+			inputCount: r,
+			activate:   act,
+		}
+		for i := 0; i < r; i++ {
+			for j, v := range n.Layers[k].Weights.RawRowView(i) {
+				l.weights[j*r+i] = v
+			}
+		}
+		for i := range l.bias {
+			l.bias[i] = n.Layers[k].BiasWeights.At(i, 0)
+		}
+		c.layers[k] = l
+	}
+	last := c.layers[len(c.layers)-1]
+	for i := range c.output {
+		c.output[i] = last.nodes[i*last.nodesCount : (i+1)*last.nodesCount]
+	}
+	return c, nil
+}
+
+// Predict passes the inputs through the network and returns the output rows.
+// The returned rows are only valid until the next call to Predict
+func (c *Compiled) Predict(in [][]float64) ([][]float64, error) {
+	if len(c.preprocessing) > 0 {
+		var err error
+		if in, err = c.preprocessing.transformBatch(in, c.BatchSize); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkRows(in, c.BatchSize, c.InputCount, -1, ErrWrongInputsCount); err != nil {
+		return nil, err
+	}
+	for k := range in {
+		row := c.input[k*c.InputCount : (k+1)*c.InputCount]
+		copy(row, in[k])
+		if c.normalization != nil {
+			c.normalization.apply(row)
+		}
+	}
+	prev := c.input
+	for i := range c.layers {
+		l := &c.layers[i]
+		b := 0
+		// Blocks of 4 rows load every weight once for the whole block
+		for ; b+4 <= len(in); b += 4 {
+			l.forwardRows4(prev[b*l.inputCount:(b+4)*l.inputCount], l.nodes[b*l.nodesCount:(b+4)*l.nodesCount])
+		}
+		for ; b < len(in); b++ {
+			l.forwardRow(prev[b*l.inputCount:(b+1)*l.inputCount], l.nodes[b*l.nodesCount:(b+1)*l.nodesCount])
+		}
+		prev = l.nodes
+	}
+	return c.output[:len(in)], nil
+}
+
+// forwardRow computes one output row of the layer from one input row.
+// The weights are stored transposed so every node is a dot product of two
+// contiguous slices, blocks of 4 nodes load every input once for the block
+func (l *compiledLayer) forwardRow(in, out []float64) {
+	ic := l.inputCount
+	k := 0
+	for ; k+4 <= len(out); k += 4 {
+		w0 := l.weights[k*ic : (k+1)*ic][:len(in)]
+		w1 := l.weights[(k+1)*ic : (k+2)*ic][:len(in)]
+		w2 := l.weights[(k+2)*ic : (k+3)*ic][:len(in)]
+		w3 := l.weights[(k+3)*ic : (k+4)*ic][:len(in)]
+		var s0, s1, s2, s3 float64
+		for j, x := range in {
+			s0 += x * w0[j]
+			s1 += x * w1[j]
+			s2 += x * w2[j]
+			s3 += x * w3[j]
+		}
+		out[k] = l.bias[k] + s0
+		out[k+1] = l.bias[k+1] + s1
+		out[k+2] = l.bias[k+2] + s2
+		out[k+3] = l.bias[k+3] + s3
+	}
+	for ; k < len(out); k++ {
+		out[k] = l.bias[k] + dot(in, l.weights[k*ic:(k+1)*ic])
+	}
+	l.activate(out)
+}
+
+// forwardRows4 computes four consecutive output rows of the layer
+func (l *compiledLayer) forwardRows4(in, out []float64) {
+	ic, nc := l.inputCount, l.nodesCount
+	in0, in1, in2, in3 := in[:ic], in[ic:2*ic], in[2*ic:3*ic], in[3*ic:4*ic]
+	for k := 0; k < nc; k++ {
+		w := l.weights[k*ic : (k+1)*ic]
+		in0, in1, in2, in3 := in0[:len(w)], in1[:len(w)], in2[:len(w)], in3[:len(w)]
+		var s0, s1, s2, s3 float64
+		for j, v := range w {
+			s0 += in0[j] * v
+			s1 += in1[j] * v
+			s2 += in2[j] * v
+			s3 += in3[j] * v
+		}
+		out[k] = l.bias[k] + s0
+		out[nc+k] = l.bias[k] + s1
+		out[2*nc+k] = l.bias[k] + s2
+		out[3*nc+k] = l.bias[k] + s3
+	}
+	for b := 0; b < 4; b++ {
+		l.activate(out[b*nc : (b+1)*nc])
+	}
+}
+
+// dot returns the dot product of a and b using independent accumulators.
+// The fixed size subslices let the compiler drop the bounds checks of the loop
+func dot(a, b []float64) float64 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3, s4, s5, s6, s7 float64
+	i := 0
+	for ; i+8 <= len(a); i += 8 {
+		x, y := a[i:i+8:i+8], b[i:i+8:i+8]
+		s0 += x[0] * y[0]
+		s1 += x[1] * y[1]
+		s2 += x[2] * y[2]
+		s3 += x[3] * y[3]
+		s4 += x[4] * y[4]
+		s5 += x[5] * y[5]
+		s6 += x[6] * y[6]
+		s7 += x[7] * y[7]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return ((s0 + s1) + (s2 + s3)) + ((s4 + s5) + (s6 + s7))
+}
@@ -0,0 +1,96 @@
+package neuro
+
+import (
+	"math"
+	"testing"
+)
+
+var (
+	smallNodes  = []int{3, 10, 5, 10}
+	mediumNodes = []int{32, 64, 32, 10}
+	// compiledData is the network of the nodes, with softmax groups of 5
+	compiledData = NetData{
+		Activations:  []string{"tanh", "sigmoid", "softmax"},
+		SplitSoftmax: 5,
+	}
+)
+
+func TestCompiledPredict(t *testing.T) {
+	data := compiledData
+	data.Nodes, data.BatchSize = mediumNodes, 4
+	n := testNetwork(t, data)
+	in := randomRows(n, 4)
+	c, err := n.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	want := n.GetOutput()
+	got, err := c.Predict(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range want {
+		for i := range want[k] {
+			if math.Abs(want[k][i]-got[k][i]) > 1e-12 {
+				t.Errorf("Row %d output %d: got %v, want %v", k, i, got[k][i], want[k][i])
+			}
+		}
+	}
+	// A partial batch only returns its own rows
+	got, err = c.Predict(in[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Errorf("Expected 1 output row, got %d", len(got))
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		c.Predict(in)
+	})
+	if allocs != 0 {
+		t.Errorf("Predict allocates %v times per call", allocs)
+	}
+}
+
+func benchmarkForward(b *testing.B, nodes []int, batchSize int) {
+	data := compiledData
+	data.Nodes, data.BatchSize = nodes, batchSize
+	n := testNetwork(b, data)
+	in := randomRows(n, batchSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := n.Forward(in); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkCompiledPredict(b *testing.B, nodes []int, batchSize int) {
+	data := compiledData
+	data.Nodes, data.BatchSize = nodes, batchSize
+	n := testNetwork(b, data)
+	in := randomRows(n, batchSize)
+	c, err := n.Compile()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Predict(in); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNetworkForwardSmall1(b *testing.B)   { benchmarkForward(b, smallNodes, 1) }
+func BenchmarkNetworkForwardSmall8(b *testing.B)   { benchmarkForward(b, smallNodes, 8) }
+func BenchmarkNetworkForwardMedium1(b *testing.B)  { benchmarkForward(b, mediumNodes, 1) }
+func BenchmarkNetworkForwardMedium8(b *testing.B)  { benchmarkForward(b, mediumNodes, 8) }
+func BenchmarkCompiledPredictSmall1(b *testing.B)  { benchmarkCompiledPredict(b, smallNodes, 1) }
+func BenchmarkCompiledPredictSmall8(b *testing.B)  { benchmarkCompiledPredict(b, smallNodes, 8) }
+func BenchmarkCompiledPredictMedium1(b *testing.B) { benchmarkCompiledPredict(b, mediumNodes, 1) }
+func BenchmarkCompiledPredictMedium8(b *testing.B) { benchmarkCompiledPredict(b, mediumNodes, 8) }
@@ -0,0 +1,172 @@
+package neuro
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CSVOptions selects the columns of a CSV or TSV file
+type CSVOptions struct {
+	// Comma is the field separator, ',' when 0
+	Comma rune
+	// Header skips the first record, which names the columns
+	Header bool
+	// Inputs and Targets list the columns by header name or by index from 0.
+	// Without Inputs every column that is not a target is an input
+	Inputs  []string
+	Targets []string
+}
+
+// LoadCSV reads a dataset from a CSV file
+func LoadCSV(path string, opts CSVOptions) (*MemoryDataset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadCSV(file, opts)
+}
+
+// LoadTSV reads a dataset from a file of tab separated values
+func LoadTSV(path string, opts CSVOptions) (*MemoryDataset, error) {
+	opts.Comma = '\t'
+	return LoadCSV(path, opts)
+}
+
+// ReadCSV reads a dataset from CSV records. Empty fields and the "NA" and "?"
+// markers are read as NaN, for an imputer to fill before training
+func ReadCSV(r io.Reader, opts CSVOptions) (*MemoryDataset, error) {
+	return readDataset(NewCSVReader(r, opts))
+}
+
+// csvReader reads the samples of CSV records one by one
+type csvReader struct {
+	r       *csv.Reader
+	opts    CSVOptions
+	header  []string
+	line    int
+	inputs  []int
+	targets []int
+}
+
+// NewCSVReader returns a SampleReader of CSV records, see ReadCSV
+func NewCSVReader(r io.Reader, opts CSVOptions) SampleReader {
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.LazyQuotes = cr.Comma == '\t'
+	cr.ReuseRecord = true
+	return &csvReader{r: cr, opts: opts}
+}
+
+// Read returns the input and the target of the next record, io.EOF at the end
+func (c *csvReader) Read() ([]float64, []float64, error) {
+	if c.line == 0 && c.opts.Header {
+		record, err := c.r.Read()
+		if err != nil {
+			return nil, nil, err
+		}
+		c.header = append([]string(nil), record...)
+		c.line++
+	}
+	record, err := c.r.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	c.line++
+	if c.inputs == nil {
+		if c.inputs, c.targets, err = c.opts.columns(c.header, len(record)); err != nil {
+			return nil, nil, err
+		}
+	}
+	in, err := csvValues(record, c.inputs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: line %d: %v", ErrDataset, c.line, err)
+	}
+	target, err := csvValues(record, c.targets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: line %d: %v", ErrDataset, c.line, err)
+	}
+	return in, target, nil
+}
+
+// CSVDecoder returns the decoder of CSV streams
+func CSVDecoder(opts CSVOptions) func(io.Reader) (SampleReader, error) {
+	return func(r io.Reader) (SampleReader, error) {
+		return NewCSVReader(r, opts), nil
+	}
+}
+
+// columns returns the indexes of the input and the target columns of records of count fields
+func (opts CSVOptions) columns(header []string, count int) ([]int, []int, error) {
+	targets, err := csvColumns(opts.Targets, header, count)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Inputs != nil {
+		inputs, err := csvColumns(opts.Inputs, header, count)
+		return inputs, targets, err
+	}
+	isTarget := map[int]bool{}
+	for _, c := range targets {
+		isTarget[c] = true
+	}
+	inputs := []int{}
+	for c := 0; c < count; c++ {
+		if !isTarget[c] {
+			inputs = append(inputs, c)
+		}
+	}
+	return inputs, targets, nil
+}
+
+// csvColumns returns the indexes of columns named in the header or given as indexes
+func csvColumns(names, header []string, count int) ([]int, error) {
+	columns := make([]int, 0, len(names))
+	for _, name := range names {
+		c := -1
+		for k, h := range header {
+			if strings.TrimSpace(h) == name {
+				c = k
+				break
+			}
+		}
+		if c < 0 {
+			if k, err := strconv.Atoi(name); err == nil && k >= 0 && k < count {
+				c = k
+			}
+		}
+		if c < 0 {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrDataset, name)
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// csvValues parses the fields of the columns
+func csvValues(record []string, columns []int) ([]float64, error) {
+	values := make([]float64, len(columns))
+	for k, c := range columns {
+		if c >= len(record) {
+			return nil, fmt.Errorf("missing column %d", c)
+		}
+		field := strings.TrimSpace(record[c])
+		if field == "" || field == "NA" || field == "?" {
+			values[k] = math.NaN()
+			continue
+		}
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[k] = v
+	}
+	return values, nil
+}
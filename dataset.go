@@ -0,0 +1,63 @@
+package neuro
+
+import (
+	"io"
+	"math/rand"
+)
+
+type (
+	// Dataset is a set of samples, every sample has an input row and a target row
+	Dataset interface {
+		// Len returns the number of samples
+		Len() int
+		// Batch returns the inputs and the targets of the samples from start to end
+		Batch(start, end int) (in, target [][]float64)
+		// Shuffle reorders the samples with the random generator
+		Shuffle(rng *rand.Rand)
+	}
+	// SampleReader reads the samples of a file one by one
+	SampleReader interface {
+		// Read returns the input and the target of the next sample, io.EOF after the last one
+		Read() (in, target []float64, err error)
+	}
+	// MemoryDataset is a Dataset held in memory. Batch returns the rows
+	// themselves, they must not be changed by the caller
+	MemoryDataset struct {
+		Inputs  [][]float64
+		Targets [][]float64
+	}
+)
+
+// Len returns the number of samples
+func (d *MemoryDataset) Len() int {
+	return len(d.Inputs)
+}
+
+// Batch returns the inputs and the targets of the samples from start to end
+func (d *MemoryDataset) Batch(start, end int) ([][]float64, [][]float64) {
+	return d.Inputs[start:end], d.Targets[start:end]
+}
+
+// Shuffle reorders the samples, keeping every input with its target
+func (d *MemoryDataset) Shuffle(rng *rand.Rand) {
+	rng.Shuffle(len(d.Inputs), func(i, j int) {
+		d.Inputs[i], d.Inputs[j] = d.Inputs[j], d.Inputs[i]
+		d.Targets[i], d.Targets[j] = d.Targets[j], d.Targets[i]
+	})
+}
+
+// readDataset reads all the samples of r in to memory
+func readDataset(r SampleReader) (*MemoryDataset, error) {
+	d := &MemoryDataset{}
+	for {
+		in, target, err := r.Read()
+		if err == io.EOF {
+			return d, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		d.Inputs = append(d.Inputs, in)
+		d.Targets = append(d.Targets, target)
+	}
+}
@@ -0,0 +1,114 @@
+package neuro
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	data := "a,b,label,c\n1,2,0,3\n4,,1,NA\n"
+	d, err := ReadCSV(strings.NewReader(data), CSVOptions{Header: true, Targets: []string{"label"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != 2 || len(d.Inputs[0]) != 3 || d.Inputs[0][2] != 3 || d.Targets[1][0] != 1 {
+		t.Errorf("Unexpected dataset %v %v", d.Inputs, d.Targets)
+	}
+	if !math.IsNaN(d.Inputs[1][1]) || !math.IsNaN(d.Inputs[1][2]) {
+		t.Errorf("Missing fields should be NaN, got %v", d.Inputs[1])
+	}
+	// Columns selected by index, without a header
+	d, err = ReadCSV(strings.NewReader("1,2,0,3\n"), CSVOptions{Inputs: []string{"3", "0"}, Targets: []string{"2", "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Inputs[0][0] != 3 || d.Inputs[0][1] != 1 || d.Targets[0][1] != 2 {
+		t.Errorf("Unexpected dataset %v %v", d.Inputs, d.Targets)
+	}
+	if _, err := ReadCSV(strings.NewReader(data), CSVOptions{Header: true, Targets: []string{"d"}}); !errors.Is(err, ErrDataset) {
+		t.Errorf("Expected ErrDataset for an unknown column, got %v", err)
+	}
+	if _, err := ReadCSV(strings.NewReader("1,x\n"), CSVOptions{Targets: []string{"0"}}); !errors.Is(err, ErrDataset) {
+		t.Errorf("Expected ErrDataset for a bad value, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "data.tsv")
+	if err := ioutil.WriteFile(path, []byte("x\ty\n0.5\t1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err = LoadTSV(path, CSVOptions{Header: true, Targets: []string{"y"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Inputs[0][0] != 0.5 || d.Targets[0][0] != 1 {
+		t.Errorf("Unexpected dataset %v %v", d.Inputs, d.Targets)
+	}
+}
+
+func TestReadLIBSVM(t *testing.T) {
+	data := "+1 1:0.5 3:2 # comment\n-1 2:1\n\n+1 4:-1\n"
+	d, err := ReadLIBSVM(strings.NewReader(data), LIBSVMOptions{OneHot: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != 3 || len(d.Inputs[0]) != 4 || d.Inputs[0][2] != 2 || d.Inputs[2][3] != -1 {
+		t.Errorf("Unexpected inputs %v", d.Inputs)
+	}
+	if d.Targets[0][1] != 1 || d.Targets[1][0] != 1 {
+		t.Errorf("Unexpected targets %v", d.Targets)
+	}
+	d, err = ReadLIBSVM(strings.NewReader(data), LIBSVMOptions{Features: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Inputs[1]) != 6 || d.Targets[1][0] != -1 {
+		t.Errorf("Unexpected dataset %v %v", d.Inputs, d.Targets)
+	}
+	if _, err := ReadLIBSVM(strings.NewReader("1 0:2\n"), LIBSVMOptions{}); !errors.Is(err, ErrDataset) {
+		t.Errorf("Expected ErrDataset for index 0, got %v", err)
+	}
+	for _, data := range []string{"1 2000000000:1\n", "1 9000000000000000000:1\n", "1 1:1\n0 300000000:1\n"} {
+		if _, err := ReadLIBSVM(strings.NewReader(data), LIBSVMOptions{}); !errors.Is(err, ErrDataset) {
+			t.Errorf("Expected ErrDataset for the width of %q, got %v", data, err)
+		}
+	}
+}
+
+func TestDatasetTraining(t *testing.T) {
+	d := &MemoryDataset{}
+	for k := 0; k < 8; k++ {
+		x := float64(k) / 8
+		d.Inputs = append(d.Inputs, []float64{x, 1 - x})
+		d.Targets = append(d.Targets, []float64{x})
+	}
+	n, err := New(NetData{Nodes: []int{2, 4, 1}, Activations: []string{"tanh", "sigmoid"}, BatchSize: 3, Train: true, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.LearnRate = 0.1
+	d.Shuffle(rand.New(rand.NewSource(1)))
+	for k, in := range d.Inputs {
+		if in[0] != d.Targets[k][0] {
+			t.Fatal("Shuffle separated an input from its target")
+		}
+	}
+	// The last batch holds the 2 remaining samples
+	for start := 0; start < d.Len(); start += n.BatchSize {
+		end := start + n.BatchSize
+		if end > d.Len() {
+			end = d.Len()
+		}
+		in, target := d.Batch(start, end)
+		if err := n.Forward(in); err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Backward(target); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
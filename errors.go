@@ -0,0 +1,94 @@
+package neuro
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The errors returned by the network, compare them with errors.Is. Their
+// messages are the ERROR_ constants
+var (
+	ErrActivationCount    = errors.New(ERROR_ACTIVATION_COUNT)
+	ErrLayersCount        = errors.New(ERROR_LAYERS_COUNT)
+	ErrUnknownActivation  = errors.New(ERROR_UNKNOWN_ACTIVATION)
+	ErrMomentum           = errors.New(ERROR_MOMENTUM)
+	ErrIntPositive        = errors.New(ERROR_INT_POSITIVE)
+	ErrWrongInputsCount   = errors.New(ERROR_WRONG_INPUTS_COUNT)
+	ErrWrongBatchCount    = errors.New(ERROR_WRONG_BATCH_COUNT)
+	ErrBatchSize          = errors.New(ERROR_BATCHSIZE)
+	ErrDimensionsMismatch = errors.New(ERROR_DIMENSIONS_MISMATCH)
+	ErrLearnRate          = errors.New(ERROR_LEARN_RATE)
+	ErrNotTrainable       = errors.New(ERROR_NOT_TRAINABLE)
+	ErrLayersImport       = errors.New(ERROR_LAYERS_IMPORT)
+	ErrWeightMismatch     = errors.New(ERROR_WEIGHT_MISMATCH)
+	ErrUnknownPrecision   = errors.New(ERROR_UNKNOWN_PRECISION)
+	ErrNormalization      = errors.New(ERROR_NORMALIZATION)
+	ErrFormatVersion      = errors.New(ERROR_FORMAT_VERSION)
+	ErrModelLoss          = errors.New(ERROR_MODEL_LOSS)
+	ErrUnknownFormat      = errors.New(ERROR_UNKNOWN_FORMAT)
+	ErrBinaryFormat       = errors.New(ERROR_BINARY_FORMAT)
+	ErrProtobuf           = errors.New(ERROR_PROTOBUF)
+	ErrONNXOperator       = errors.New(ERROR_ONNX_OPERATOR)
+	ErrONNXGraph          = errors.New(ERROR_ONNX_GRAPH)
+	ErrNPYFormat          = errors.New(ERROR_NPY_FORMAT)
+	ErrSoftmaxGroups      = errors.New(ERROR_SOFTMAX_GROUPS)
+	ErrHeads              = errors.New(ERROR_HEADS)
+	ErrCheckpoint         = errors.New(ERROR_CHECKPOINT)
+	ErrNotFinite          = errors.New(ERROR_NOT_FINITE)
+	ErrDataset            = errors.New(ERROR_DATASET)
+	ErrRewind             = errors.New(ERROR_REWIND)
+	ErrIDXFormat          = errors.New(ERROR_IDX_FORMAT)
+	ErrPreprocessing      = errors.New(ERROR_PREPROCESSING)
+	ErrLabels             = errors.New(ERROR_LABELS)
+)
+
+// ShapeError reports values whose shape does not match the network. It
+// matches its Err with errors.Is, ErrDimensionsMismatch when Err is nil
+type ShapeError struct {
+	// Layer is the index of the layer, -1 when the values do not belong to a layer
+	Layer int
+	Want  []int
+	Got   []int
+	Err   error
+}
+
+func (e *ShapeError) Error() string {
+	if e.Layer < 0 {
+		return fmt.Sprintf("%v: want shape %v, got %v", e.Unwrap(), e.Want, e.Got)
+	}
+	return fmt.Sprintf("%v: layer %d: want shape %v, got %v", e.Unwrap(), e.Layer, e.Want, e.Got)
+}
+
+func (e *ShapeError) Unwrap() error {
+	if e.Err == nil {
+		return ErrDimensionsMismatch
+	}
+	return e.Err
+}
+
+// atLayer sets the layer of a ShapeError returned without one
+func atLayer(err error, layer int) error {
+	var shape *ShapeError
+	if errors.As(err, &shape) && shape.Layer < 0 {
+		shape.Layer = layer
+	}
+	return err
+}
+
+// ValueError reports a NaN or infinite value, it matches ErrNotFinite with errors.Is
+type ValueError struct {
+	// Layer is the index of the layer whose output holds the value, -1 for inputs and targets
+	Layer int
+	Row   int
+	Col   int
+	Value float64
+}
+
+func (e *ValueError) Error() string {
+	if e.Layer < 0 {
+		return fmt.Sprintf("%v: %v at row %d, column %d", ErrNotFinite, e.Value, e.Row, e.Col)
+	}
+	return fmt.Sprintf("%v: layer %d: %v at row %d, column %d", ErrNotFinite, e.Layer, e.Value, e.Row, e.Col)
+}
+
+func (e *ValueError) Unwrap() error { return ErrNotFinite }
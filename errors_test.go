@@ -0,0 +1,102 @@
+package neuro
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	if _, err := New(NetData{Nodes: []int{2}, Activations: []string{}, BatchSize: 1}); !errors.Is(err, ErrLayersCount) {
+		t.Errorf("New: got %v, want ErrLayersCount", err)
+	}
+	_, err := New(NetData{
+		Nodes:       []int{2, 3},
+		Activations: []string{"sigmoid"},
+		BatchSize:   1,
+		WeightsData: []DataWeights{{Weights: []float64{1, 2, 3, 4, 5, 6}, BiasWeights: []float64{1, 2}}},
+	})
+	var shape *ShapeError
+	if !errors.As(err, &shape) || !errors.Is(err, ErrWeightMismatch) || shape.Layer != 0 || shape.Want[0] != 3 || shape.Got[0] != 2 {
+		t.Errorf("New: got %v, want a ShapeError of the bias of layer 0", err)
+	}
+
+	n, err := New(NetData{Nodes: []int{2, 3, 2}, Activations: []string{"sigmoid", "softmax"}, BatchSize: 2, Train: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Forward([][]float64{{1, 0}, {0, 1}, {1, 1}}); !errors.Is(err, ErrWrongBatchCount) {
+		t.Errorf("Forward: got %v, want ErrWrongBatchCount", err)
+	}
+	if err := n.Backward([][]float64{{1, 0}}); !errors.Is(err, ErrLearnRate) {
+		t.Errorf("Backward: got %v, want ErrLearnRate", err)
+	}
+	_, err = n.NetError([][]float64{{1, 0}})
+	if !errors.As(err, &shape) || !errors.Is(err, ErrDimensionsMismatch) || shape.Layer != 1 {
+		t.Errorf("NetError: got %v, want a ShapeError of layer 1", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := ioutil.WriteFile(path, []byte(`{"FormatVersion": 99}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(path, 1, false); !errors.Is(err, ErrFormatVersion) {
+		t.Errorf("Import: got %v, want ErrFormatVersion", err)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	n, err := New(NetData{Nodes: []int{2, 3, 2}, Activations: []string{"sigmoid", "softmax"}, BatchSize: 2, Train: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.LearnRate = 0.1
+	var shape *ShapeError
+	if err := n.Forward([][]float64{{1, 0}, {1, 0, 1}}); !errors.As(err, &shape) || !errors.Is(err, ErrWrongInputsCount) {
+		t.Errorf("Forward: got %v, want a ShapeError of the inputs", err)
+	}
+	var value *ValueError
+	if err := n.Forward([][]float64{{1, 0}, {math.NaN(), 0}}); !errors.As(err, &value) || value.Row != 1 || value.Col != 0 {
+		t.Errorf("Forward: got %v, want a ValueError at row 1, column 0", err)
+	}
+	if err := n.Forward([][]float64{{1, 0}, {0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Backward([][]float64{{1, 0}, {0}}); !errors.As(err, &shape) || shape.Layer != 1 {
+		t.Errorf("Backward: got %v, want a ShapeError of layer 1", err)
+	}
+	if err := n.Backward([][]float64{{1, 0}, {math.Inf(1), 0}}); !errors.Is(err, ErrNotFinite) {
+		t.Errorf("Backward: got %v, want ErrNotFinite", err)
+	}
+	if _, err := n.NetError([][]float64{{1, 0, 0}, {0, 1, 0}}); !errors.As(err, &shape) {
+		t.Errorf("NetError: got %v, want a ShapeError", err)
+	}
+	c, err := n.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Predict([][]float64{{1}}); !errors.Is(err, ErrWrongInputsCount) {
+		t.Errorf("Predict: got %v, want ErrWrongInputsCount", err)
+	}
+	n32, err := n.Float32()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n32.Forward([][]float32{{1, float32(math.NaN())}}); !errors.Is(err, ErrNotFinite) {
+		t.Errorf("Float32 Forward: got %v, want ErrNotFinite", err)
+	}
+
+	// Inf - Inf gives a NaN in the first layer that only the strict mode reports
+	n.Layers[0].Weights.Set(0, 0, math.Inf(1))
+	n.Layers[0].Weights.Set(1, 0, math.Inf(-1))
+	in := [][]float64{{1, 1}, {1, 1}}
+	if err := n.Forward(in); err != nil {
+		t.Errorf("Forward: got %v, want no error outside the strict mode", err)
+	}
+	n.Strict = true
+	if err := n.Forward(in); !errors.As(err, &value) || value.Layer != 0 {
+		t.Errorf("Strict Forward: got %v, want a ValueError of layer 0", err)
+	}
+}
@@ -0,0 +1,188 @@
+package neuro
+
+type (
+	// Network32 is a float32 copy of a network used for inference.
+	// It halves the memory of the weights and the activations compared to the
+	// float64 network, training is only supported by the float64 network.
+	Network32 struct {
+		Layers      []Layer32
+		Activations []string
+		InputCount  int
+		OutputLayer int
+		BatchSize   int
+		// preprocessing and normalization are applied to the input rows, like in Network.Forward
+		preprocessing Pipeline
+		normalization *Normalization
+		input         []float32
+	}
+	// Layer32 holds the weights of a layer as row-major float32 slices
+	Layer32 struct {
+		Nodes       []float32
+		Weights     []float32
+		BiasWeights []float32
+		NodesCount  int
+		InputCount  int
+		activate    func([]float32)
+	}
+)
+
+// Float32 activation functions, registered by the activation files
+var activation32Map = map[string]func([]float32){}
+
+// activator32 is implemented by the activation functions configured per
+// layer, like softmax with its groups, instead of an activation32Map entry
+type activator32 interface {
+	activate32([]float32)
+}
+
+// Float32 returns a float32 inference copy of the network
+func (n *Network) Float32() (*Network32, error) {
+	n32 := &Network32{
+		Layers:        make([]Layer32, len(n.Layers)),
+		Activations:   n.Activations,
+		InputCount:    n.InputCount,
+		OutputLayer:   n.OutputLayer,
+		BatchSize:     n.BatchSize,
+		preprocessing: n.Preprocessing.clone(),
+		normalization: n.Normalization.clone(),
+		input:         make([]float32, n.BatchSize*n.InputCount),
+	}
+	for k := range n.Layers {
+		act, ok := activation32Map[n.Activations[k]]
+		if a, is32 := n.Layers[k].Activation.(activator32); is32 {
+			act, ok = a.activate32, true
+		}
+		if !ok {
+			return nil, ErrUnknownActivation
+		}
+		r, c := n.Layers[k].Weights.Dims()
+		l := Layer32{
+			Nodes:       make([]float32, n.BatchSize*c),
+			Weights:     make([]float32, r*c),
+			BiasWeights: make([]float32, c),
+			NodesCount:  c,
+			InputCount:  r,
+			activate:    act,
+		}
+		for i := 0; i < r; i++ {
+			for j, v := range n.Layers[k].Weights.RawRowView(i) {
+				l.Weights[i*c+j] = float32(v)
+			}
+		}
+		for i := range l.BiasWeights {
+			l.BiasWeights[i] = float32(n.Layers[k].BiasWeights.At(i, 0))
+		}
+		n32.Layers[k] = l
+	}
+	return n32, nil
+}
+
+// Forward takes inputs and passes through the network
+func (n *Network32) Forward(in [][]float32) error {
+	if len(n.preprocessing) > 0 {
+		var err error
+		if in, err = n.preprocess(in); err != nil {
+			return err
+		}
+	}
+	if err := checkRows32(in, n.BatchSize, n.InputCount); err != nil {
+		return err
+	}
+	for k := range in {
+		row := n.input[k*n.InputCount : (k+1)*n.InputCount]
+		copy(row, in[k])
+		if n.normalization != nil {
+			n.normalization.apply32(row)
+		}
+	}
+	prev := n.input
+	for i := range n.Layers {
+		l := &n.Layers[i]
+		for b := 0; b < n.BatchSize; b++ {
+			row := l.Nodes[b*l.NodesCount : (b+1)*l.NodesCount]
+			copy(row, l.BiasWeights)
+			for j, a := range prev[b*l.InputCount : (b+1)*l.InputCount] {
+				if a == 0 {
+					continue
+				}
+				for c, w := range l.Weights[j*l.NodesCount : (j+1)*l.NodesCount] {
+					row[c] += a * w
+				}
+			}
+			l.activate(row)
+		}
+		prev = l.Nodes
+	}
+	return nil
+}
+
+// preprocess runs the rows through the pipeline at float64 precision
+func (n *Network32) preprocess(in [][]float32) ([][]float32, error) {
+	rows := make([][]float64, len(in))
+	for k, row := range in {
+		rows[k] = make([]float64, len(row))
+		for c, v := range row {
+			rows[k][c] = float64(v)
+		}
+	}
+	rows, err := n.preprocessing.transformBatch(rows, n.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]float32, len(rows))
+	for k, row := range rows {
+		out[k] = make([]float32, len(row))
+		for c, v := range row {
+			out[k][c] = float32(v)
+		}
+	}
+	return out, nil
+}
+
+// GetOutput returns the values from the last layer of the network
+func (n *Network32) GetOutput() [][]float32 {
+	l := n.Layers[n.OutputLayer]
+	output := make([][]float32, n.BatchSize)
+	for i := range output {
+		output[i] = append([]float32(nil), l.Nodes[i*l.NodesCount:(i+1)*l.NodesCount]...)
+	}
+	return output
+}
+
+// widenWeights converts weights stored with float32 precision to float64
+func widenWeights(data []DataWeights) []DataWeights {
+	out := make([]DataWeights, len(data))
+	for k := range data {
+		out[k] = data[k]
+		if out[k].Weights == nil && out[k].Weights32 != nil {
+			out[k].Weights = make([]float64, len(data[k].Weights32))
+			for i, v := range data[k].Weights32 {
+				out[k].Weights[i] = float64(v)
+			}
+		}
+		if out[k].BiasWeights == nil && out[k].BiasWeights32 != nil {
+			out[k].BiasWeights = make([]float64, len(data[k].BiasWeights32))
+			for i, v := range data[k].BiasWeights32 {
+				out[k].BiasWeights[i] = float64(v)
+			}
+		}
+		out[k].Weights32, out[k].BiasWeights32 = nil, nil
+	}
+	return out
+}
+
+// narrowWeights converts weights to float32 precision for storage
+func narrowWeights(data []DataWeights) []DataWeights {
+	out := make([]DataWeights, len(data))
+	for k := range data {
+		out[k].Weights32 = make([]float32, len(data[k].Weights))
+		for i, v := range data[k].Weights {
+			out[k].Weights32[i] = float32(v)
+		}
+		out[k].BiasWeights32 = make([]float32, len(data[k].BiasWeights))
+		for i, v := range data[k].BiasWeights {
+			out[k].BiasWeights32[i] = float32(v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package neuro
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestFloat32Inference(t *testing.T) {
+	n := testNetwork(t, NetData{})
+	in := [][]float64{[]float64{1, 1, 0}, []float64{0, 1, 1}, []float64{1, 0.5, 1}}
+	if err := n.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	n32, err := n.Float32()
+	if err != nil {
+		t.Fatal(err)
+	}
+	in32 := make([][]float32, len(in))
+	for k := range in {
+		in32[k] = make([]float32, len(in[k]))
+		for i, v := range in[k] {
+			in32[k][i] = float32(v)
+		}
+	}
+	if err := n32.Forward(in32); err != nil {
+		t.Fatal(err)
+	}
+	want, got := n.GetOutput(), n32.GetOutput()
+	for k := range want {
+		for i := range want[k] {
+			if diff := math.Abs(want[k][i] - float64(got[k][i])); diff > 1e-5 {
+				t.Errorf("Row %d output %d: float32 %v differs from float64 %v by %v", k, i, got[k][i], want[k][i], diff)
+			}
+		}
+	}
+}
+
+func TestFloat32Export(t *testing.T) {
+	n, err := New(NetData{
+		Nodes:       []int{3, 10, 2},
+		Activations: []string{"tanh", "sigmoid"},
+		BatchSize:   3,
+		Precision:   "float32",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := [][]float64{[]float64{1, 1, 0}, []float64{0, 1, 1}, []float64{1, 0.5, 1}}
+	if err := n.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	data, err := n.Export("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.WeightsData[0].Weights != nil || len(data.WeightsData[0].Weights32) != 30 {
+		t.Fatal("Expected the weights to be exported with float32 precision")
+	}
+	js32, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Precision = ""
+	data64, err := n.Export("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	js64, err := json.Marshal(data64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(js32) >= len(js64) {
+		t.Errorf("Float32 export is not smaller: %d >= %d bytes", len(js32), len(js64))
+	}
+	y, err := New(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := y.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	want, got := n.GetOutput(), y.GetOutput()
+	for k := range want {
+		for i := range want[k] {
+			if diff := math.Abs(want[k][i] - got[k][i]); diff > 1e-6 {
+				t.Errorf("Row %d output %d: imported %v differs from %v by %v", k, i, got[k][i], want[k][i], diff)
+			}
+		}
+	}
+	if _, err := New(NetData{Nodes: []int{1, 1}, Activations: []string{"tanh"}, BatchSize: 1, Precision: "float16"}); err == nil {
+		t.Error("Expected an error for an unknown precision")
+	}
+}
@@ -0,0 +1,222 @@
+package neuro
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type (
+	// FrameworkModel is a simple layout of dense layers exported from Keras,
+	// PyTorch or any other framework as JSON
+	FrameworkModel struct {
+		Layers []FrameworkLayer
+	}
+	// FrameworkLayer is a dense layer. Its weights are given either as a Kernel
+	// of inputs x nodes, the Keras layout, or as a Weight of nodes x inputs, the
+	// PyTorch layout. KernelFile, WeightFile and BiasFile read the values from
+	// .npy files instead, relative to the JSON file
+	FrameworkLayer struct {
+		Kernel     [][]float64 `json:",omitempty"`
+		Weight     [][]float64 `json:",omitempty"`
+		Bias       []float64   `json:",omitempty"`
+		KernelFile string      `json:",omitempty"`
+		WeightFile string      `json:",omitempty"`
+		BiasFile   string      `json:",omitempty"`
+		Activation string
+	}
+)
+
+// activationAliases maps the activation names used by other frameworks to activationMap
+var activationAliases = map[string]string{
+	"logistic":  "sigmoid",
+	"softmax2d": "softmax",
+}
+
+// activationName returns the activationMap entry of an activation name from another framework
+func activationName(name string) (string, error) {
+	key := strings.ToLower(name)
+	if alias, ok := activationAliases[key]; ok {
+		key = alias
+	}
+	if _, ok := activationMap[key]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownActivation, name)
+	}
+	return key, nil
+}
+
+// ImportFrameworkJSON loads a network from a FrameworkModel JSON file
+func ImportFrameworkJSON(path string, batchSize int, train bool) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	model := FrameworkModel{}
+	if err := json.NewDecoder(file).Decode(&model); err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+	for k := range model.Layers {
+		l := &model.Layers[k]
+		for _, f := range []struct {
+			name   string
+			matrix *[][]float64
+		}{{l.KernelFile, &l.Kernel}, {l.WeightFile, &l.Weight}} {
+			if f.name == "" {
+				continue
+			}
+			if *f.matrix, err = readNPYFile(filepath.Join(dir, f.name)); err != nil {
+				return nil, err
+			}
+		}
+		if l.BiasFile != "" {
+			bias, err := readNPYFile(filepath.Join(dir, l.BiasFile))
+			if err != nil {
+				return nil, err
+			}
+			if l.Bias, err = vectorNPY(bias); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return model.network(batchSize, train)
+}
+
+// ImportNPZ loads a network from a NumPy .npz archive of dense weights and
+// biases, as written by numpy.savez from a Keras or PyTorch model. The arrays
+// are grouped by the prefix of their name, such as "0.weight" and "0.bias" of
+// a PyTorch state_dict or "dense/kernel:0" and "dense/bias:0" of Keras, and the
+// layers follow the order of the archive. A "weight" array is nodes x inputs,
+// a "kernel" array inputs x nodes. The activations name the layers' activation functions
+func ImportNPZ(path string, activations []string, batchSize int, train bool) (*Network, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	model := FrameworkModel{}
+	layers := map[string]int{}
+	for _, f := range archive.File {
+		name := strings.TrimSuffix(f.Name, ".npy")
+		name = strings.TrimSuffix(name, ":0")
+		split := strings.LastIndexAny(name, "./")
+		if split < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNPYFormat, f.Name)
+		}
+		prefix, kind := name[:split], name[split+1:]
+		k, ok := layers[prefix]
+		if !ok {
+			k = len(model.Layers)
+			layers[prefix] = k
+			model.Layers = append(model.Layers, FrameworkLayer{})
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		values, err := readNPY(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		l := &model.Layers[k]
+		switch kind {
+		case "kernel":
+			l.Kernel = values
+		case "weight":
+			l.Weight = values
+		case "bias":
+			if l.Bias, err = vectorNPY(values); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrNPYFormat, f.Name)
+		}
+	}
+	if len(activations) != len(model.Layers) {
+		return nil, ErrActivationCount
+	}
+	for k := range model.Layers {
+		model.Layers[k].Activation = activations[k]
+	}
+	return model.network(batchSize, train)
+}
+
+// network converts the layers to NetData and creates the network
+func (model *FrameworkModel) network(batchSize int, train bool) (*Network, error) {
+	data := NetData{BatchSize: batchSize, Train: train}
+	for k, l := range model.Layers {
+		activation, err := activationName(l.Activation)
+		if err != nil {
+			return nil, err
+		}
+		// Keras kernels are stored like the network's weights, PyTorch weights are transposed
+		kernel := l.Kernel
+		if kernel == nil {
+			kernel = transposeMatrix(l.Weight)
+		}
+		if len(kernel) == 0 || len(kernel[0]) == 0 {
+			return nil, ErrWeightMismatch
+		}
+		inputs, nodes := len(kernel), len(kernel[0])
+		if k == 0 {
+			data.Nodes = []int{inputs}
+		} else if data.Nodes[k] != inputs {
+			return nil, ErrWeightMismatch
+		}
+		weights := make([]float64, 0, inputs*nodes)
+		for _, row := range kernel {
+			if len(row) != nodes {
+				return nil, ErrWeightMismatch
+			}
+			weights = append(weights, row...)
+		}
+		bias := l.Bias
+		if bias == nil {
+			bias = make([]float64, nodes)
+		}
+		data.Nodes = append(data.Nodes, nodes)
+		data.Activations = append(data.Activations, activation)
+		data.WeightsData = append(data.WeightsData, DataWeights{Weights: weights, BiasWeights: bias})
+	}
+	return New(data)
+}
+
+// transposeMatrix transposes a matrice given as rows, ragged rows give nil
+func transposeMatrix(m [][]float64) [][]float64 {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make([][]float64, len(m[0]))
+	for c := range out {
+		out[c] = make([]float64, len(m))
+	}
+	for r := range m {
+		if len(m[r]) != len(out) {
+			return nil
+		}
+		for c, v := range m[r] {
+			out[c][r] = v
+		}
+	}
+	return out
+}
+
+// vectorNPY flattens a bias array stored as a vector or a single row
+func vectorNPY(values [][]float64) ([]float64, error) {
+	if len(values) == 1 {
+		return values[0], nil
+	}
+	vector := make([]float64, len(values))
+	for k, row := range values {
+		if len(row) != 1 {
+			return nil, ErrWeightMismatch
+		}
+		vector[k] = row[0]
+	}
+	return vector, nil
+}
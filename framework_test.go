@@ -0,0 +1,167 @@
+package neuro
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// npyBytes encodes values as a float64 .npy array of the given shape
+func npyBytes(shape string, values []float64) []byte {
+	header := fmt.Sprintf("{'descr': '<f8', 'fortran_order': False, 'shape': %s, }", shape)
+	header += strings.Repeat(" ", 63-(len(header)+10)%64) + "\n"
+	buf := &bytes.Buffer{}
+	buf.Write(npyMagic)
+	buf.Write([]byte{1, 0})
+	binary.Write(buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	binary.Write(buf, binary.LittleEndian, values)
+	return buf.Bytes()
+}
+
+// The 2-3-2 network used by the framework tests, relu then softmax
+var (
+	frameworkKernel1 = [][]float64{{1, -2, 0.5}, {0.25, 1, -1}}
+	frameworkBias1   = []float64{0.1, 0, -0.1}
+	frameworkKernel2 = [][]float64{{1, 0}, {-1, 1}, {0.5, 2}}
+	frameworkBias2   = []float64{0, 0.5}
+)
+
+func frameworkWant(in []float64) []float64 {
+	hidden := make([]float64, 3)
+	for c := range hidden {
+		hidden[c] = frameworkBias1[c]
+		for r, v := range in {
+			hidden[c] += v * frameworkKernel1[r][c]
+		}
+		hidden[c] = math.Max(hidden[c], 0)
+	}
+	out := make([]float64, 2)
+	var sum float64
+	for c := range out {
+		out[c] = frameworkBias2[c]
+		for r, v := range hidden {
+			out[c] += v * frameworkKernel2[r][c]
+		}
+		out[c] = math.Exp(out[c])
+		sum += out[c]
+	}
+	for c := range out {
+		out[c] /= sum
+	}
+	return out
+}
+
+func assertFrameworkNetwork(t *testing.T, n *Network) {
+	in := [][]float64{{0.5, -1}}
+	if err := n.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	want := frameworkWant(in[0])
+	for k, v := range n.GetOutput()[0] {
+		if math.Abs(v-want[k]) > 1e-12 {
+			t.Errorf("Output %d: got %v, want %v", k, v, want[k])
+		}
+	}
+}
+
+func TestImportFrameworkJSON(t *testing.T) {
+	dir := t.TempDir()
+	// A PyTorch layer with its weight transposed, then a Keras layer reading .npy files
+	if err := ioutil.WriteFile(filepath.Join(dir, "kernel.npy"), npyBytes("(3, 2)", []float64{1, 0, -1, 1, 0.5, 2}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "bias.npy"), npyBytes("(2,)", frameworkBias2), 0644); err != nil {
+		t.Fatal(err)
+	}
+	js := `{"layers": [
+		{"weight": [[1, 0.25], [-2, 1], [0.5, -1]], "bias": [0.1, 0, -0.1], "activation": "ReLU"},
+		{"kernelFile": "kernel.npy", "biasFile": "bias.npy", "activation": "softmax"}
+	]}`
+	path := filepath.Join(dir, "model.json")
+	if err := ioutil.WriteFile(path, []byte(js), 0644); err != nil {
+		t.Fatal(err)
+	}
+	n, err := ImportFrameworkJSON(path, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Activations[0] != "relu" || n.Activations[1] != "softmax" {
+		t.Errorf("Unexpected activations %v", n.Activations)
+	}
+	assertFrameworkNetwork(t, n)
+
+	js = `{"layers": [{"kernel": [[1, 2]], "activation": "linear"}]}`
+	if err := ioutil.WriteFile(path, []byte(js), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportFrameworkJSON(path, 1, false); err == nil || !strings.Contains(err.Error(), "linear") {
+		t.Errorf("Expected an unknown activation error, got %v", err)
+	}
+}
+
+func TestImportNPZ(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.npz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zip.NewWriter(file)
+	// A PyTorch state_dict, the weights are nodes x inputs
+	arrays := []struct {
+		name   string
+		values []byte
+	}{
+		{"fc1.weight.npy", npyBytes("(3, 2)", []float64{1, 0.25, -2, 1, 0.5, -1})},
+		{"fc1.bias.npy", npyBytes("(3,)", frameworkBias1)},
+		{"fc2.weight.npy", npyBytes("(2, 3)", []float64{1, -1, 0.5, 0, 1, 2})},
+		{"fc2.bias.npy", npyBytes("(2,)", frameworkBias2)},
+	}
+	for _, a := range arrays {
+		w, err := archive.Create(a.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(a.values)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	n, err := ImportNPZ(path, []string{"relu", "Softmax"}, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFrameworkNetwork(t, n)
+	if _, err := ImportNPZ(path, []string{"relu"}, 1, false); err == nil {
+		t.Error("Expected an error for a missing activation")
+	}
+}
+
+func TestReadNPYFortran(t *testing.T) {
+	data := npyBytes("(2, 3)", []float64{1, 4, 2, 5, 3, 6})
+	data = bytes.Replace(data, []byte("False"), []byte("True "), 1)
+	values, err := readNPY(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values[0][1] != 2 || values[1][0] != 4 {
+		t.Errorf("Unexpected values %v", values)
+	}
+}
+
+func TestReadNPYSize(t *testing.T) {
+	for _, shape := range []string{"(100000, 100000)", "(4611686018427387904, 4)", "(4096, 4096)", "(3, 2)"} {
+		// Only 4 of the values are in the file
+		if _, err := readNPY(bytes.NewReader(npyBytes(shape, []float64{1, 2, 3, 4}))); err == nil {
+			t.Errorf("Expected an error for a truncated %s array", shape)
+		}
+	}
+}
@@ -1,11 +1,9 @@
 package neuro
 
 import (
-	"errors"
 	"fmt"
 	"math"
 	"math/rand"
-	"runtime"
 
 	"github.com/gonum/matrix/mat64"
 )
@@ -22,10 +20,10 @@ func randomFuncVec(v *mat64.Vector, min, max float64) {
 	fmt.Println(v)
 }
 
-func randomFunc(rows, cols int, min, max float64) []float64 {
+func randomFunc(r *rand.Rand, rows, cols int, min, max float64) []float64 {
 	output := make([]float64, rows*cols)
 	for k := range output {
-		output[k] = rand.Float64()*(max-min) + min
+		output[k] = r.Float64()*(max-min) + min
 	}
 	return output
 }
@@ -38,44 +36,35 @@ func activateFloat(a []float64, f func(float64) float64) []float64 {
 	return a
 }
 
-// Error tracing function for nested functions
-func trace() string {
-	pc := make([]uintptr, 10) // at least 1 entry needed
-	runtime.Callers(2, pc)
-	f := runtime.FuncForPC(pc[0])
-	file, line := f.FileLine(pc[0])
-	return fmt.Sprintf(" on line %s:%d", file, line)
-}
-
+// calcActivate applies the activation, or its derivative, to the raw data of in and stores it in out.
+// With transpose the output is stored transposed, out can be the same matrice as in otherwise
 func calcActivate(in, out *mat64.Dense, af, df func(float64) float64, deriv, transpose bool) error {
+	f := af
+	if deriv {
+		f = df
+	}
 	rowsIn, colsIn := in.Dims()
 	rowsOut, colsOut := out.Dims()
 	if transpose {
 		if rowsIn != colsOut || colsIn != rowsOut {
-			return errors.New(fmt.Sprint(ERROR_DIMENSIONS_MISMATCH, trace()))
-		}
-		if deriv {
-			for i := 0; i < rowsIn; i++ {
-				out.SetCol(i, activateFloat(mat64.Row(nil, i, in), df))
-			}
-			return nil
+			return &ShapeError{Layer: -1, Want: []int{colsIn, rowsIn}, Got: []int{rowsOut, colsOut}}
 		}
+		raw := out.RawMatrix()
 		for i := 0; i < rowsIn; i++ {
-			out.SetCol(i, activateFloat(mat64.Row(nil, i, in), af))
+			for k, v := range in.RawRowView(i) {
+				raw.Data[k*raw.Stride+i] = f(v)
+			}
 		}
 		return nil
 	}
 	if rowsIn != rowsOut || colsIn != colsOut {
-		return errors.New(fmt.Sprint(ERROR_DIMENSIONS_MISMATCH, trace()))
-	}
-	if deriv {
-		for i := 0; i < rowsIn; i++ {
-			out.SetRow(i, activateFloat(mat64.Row(nil, i, in), df))
-		}
-		return nil
+		return &ShapeError{Layer: -1, Want: []int{rowsIn, colsIn}, Got: []int{rowsOut, colsOut}}
 	}
 	for i := 0; i < rowsIn; i++ {
-		out.SetRow(i, activateFloat(mat64.Row(nil, i, in), af))
+		dst := out.RawRowView(i)
+		for k, v := range in.RawRowView(i) {
+			dst[k] = f(v)
+		}
 	}
 	return nil
 }
@@ -114,11 +103,29 @@ func preventOverflow(v float64) float64 {
 	return v
 }
 
+// Cross entropy error of one-hot targets
+func crossEntropyError(output *mat64.Dense, target [][]float64) (float64, error) {
+	r, _ := output.Dims()
+	if len(target) != r {
+		return 0, &ShapeError{Layer: -1, Want: []int{r}, Got: []int{len(target)}}
+	}
+	netError := 0.0
+	for i := 0; i < r; i++ {
+		for k, v := range output.RawRowView(i) {
+			if target[i][k] == 1.0 {
+				netError -= math.Log(v)
+			}
+		}
+	}
+	netError = netError / float64(r)
+	return netError, nil
+}
+
 // Mean squared error
 func meanSquaredError(output *mat64.Dense, target [][]float64) (float64, error) {
 	r, _ := output.Dims()
 	if len(target) != r {
-		return 0, errors.New(ERROR_DIMENSIONS_MISMATCH)
+		return 0, &ShapeError{Layer: -1, Want: []int{r}, Got: []int{len(target)}}
 	}
 	netError := 0.0
 	for i := 0; i < r; i++ {
@@ -0,0 +1,272 @@
+package neuro
+
+import (
+	"github.com/gonum/matrix/mat64"
+)
+
+type (
+	// Head is a named output of a multi-head network. The heads split the
+	// output layer, whose activation is "heads", in consecutive groups of
+	// nodes that all read the shared trunk of hidden layers
+	Head struct {
+		Name       string
+		Size       int
+		Activation string
+		// Loss is the error of the head, "mse" or "cross-entropy", empty uses the activation's loss
+		Loss string `json:",omitempty"`
+		// Weight scales the gradients and the error of the head, 0 counts as 1
+		Weight float64 `json:",omitempty"`
+	}
+	// headsFunc is the output activation of a multi-head network, it applies
+	// the activation of every head to its own nodes
+	headsFunc struct {
+		heads  []Head
+		starts []int
+		acts   []activationFunction
+		losses []func(*mat64.Dense, [][]float64) (float64, error)
+		// direct heads use target - output as their error term, without the activation derivative
+		direct []bool
+		rows   []func([]float64)
+		rows32 []func([]float32)
+	}
+)
+
+// Error functions of the heads by loss name
+var lossMap = map[string]func(*mat64.Dense, [][]float64) (float64, error){
+	"mse":           meanSquaredError,
+	"cross-entropy": crossEntropyError,
+}
+
+func init() {
+	activationMap["heads"] = &headsFunc{}
+	onnxActivationMap["heads"] = headsONNX
+}
+
+// newHeads returns the output activation of heads that split a layer of the given size
+func newHeads(heads []Head, nodes int) (*headsFunc, error) {
+	f := &headsFunc{heads: heads}
+	names := map[string]bool{}
+	start := 0
+	for _, h := range heads {
+		act, ok := activationMap[h.Activation]
+		if !ok || h.Activation == "heads" {
+			return nil, ErrUnknownActivation
+		}
+		if h.Name == "" || names[h.Name] || h.Size < 1 || h.Weight < 0 {
+			return nil, ErrHeads
+		}
+		names[h.Name] = true
+		loss := h.Loss
+		if loss == "" {
+			loss = act.loss()
+		}
+		lossFunc, ok := lossMap[loss]
+		if !ok {
+			return nil, ErrHeads
+		}
+		// Activations configured per layer, like softmax, provide their own row kernels
+		row, row32 := activationRowMap[h.Activation], activation32Map[h.Activation]
+		if r, ok := act.(rowActivator); ok {
+			row = r.activateRow
+		}
+		if r, ok := act.(activator32); ok {
+			row32 = r.activate32
+		}
+		f.starts = append(f.starts, start)
+		f.acts = append(f.acts, act)
+		f.losses = append(f.losses, lossFunc)
+		// The cross-entropy gradient of a softmax or sigmoid output cancels out its derivative
+		f.direct = append(f.direct, loss == "cross-entropy" && (h.Activation == "softmax" || h.Activation == "sigmoid"))
+		f.rows = append(f.rows, row)
+		f.rows32 = append(f.rows32, row32)
+		start += h.Size
+	}
+	if len(heads) == 0 || start != nodes {
+		return nil, ErrHeads
+	}
+	return f, nil
+}
+
+func (h Head) weight() float64 {
+	if h.Weight == 0 {
+		return 1
+	}
+	return h.Weight
+}
+
+func (f headsFunc) activate(in, out *mat64.Dense, deriv bool, transpose bool) error {
+	rows, _ := in.Dims()
+	for k, h := range f.heads {
+		s := f.starts[k]
+		headIn := in.View(0, s, rows, h.Size).(*mat64.Dense)
+		var headOut *mat64.Dense
+		if transpose {
+			headOut = out.View(s, 0, h.Size, rows).(*mat64.Dense)
+		} else {
+			headOut = out.View(0, s, rows, h.Size).(*mat64.Dense)
+		}
+		if err := f.acts[k].activate(headIn, headOut, deriv, transpose); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f headsFunc) activateRow(a []float64) {
+	for k, h := range f.heads {
+		f.rows[k](a[f.starts[k] : f.starts[k]+h.Size])
+	}
+}
+
+func (f headsFunc) activate32(a []float32) {
+	for k, h := range f.heads {
+		f.rows32[k](a[f.starts[k] : f.starts[k]+h.Size])
+	}
+}
+
+// backpropError computes the error term of every head from its loss and
+// scales it by the head weight. The heads are always the output layer, so
+// the errors hold target - output. Cross-entropy on a softmax or sigmoid
+// head uses them as they are, the other losses multiply them by the
+// derivative of the head activation
+func (f headsFunc) backpropError(n *Network, layer int) error {
+	l := &n.Layers[layer]
+	if err := f.activate(l.Nodes, l.Derivative, true, true); err != nil {
+		return err
+	}
+	// The errors and the derivatives are stored transposed, a row per node
+	for k, h := range f.heads {
+		w := h.weight()
+		for r := f.starts[k]; r < f.starts[k]+h.Size; r++ {
+			row, deriv := l.Errors.RawRowView(r), l.Derivative.RawRowView(r)
+			for i := range row {
+				if !f.direct[k] {
+					row[i] *= deriv[i]
+				}
+				row[i] *= w
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the name of the cost function used by layerError
+func (headsFunc) loss() string { return "multi-head" }
+
+// Returns the sum of the heads errors scaled by their weights
+func (f headsFunc) layerError(output *mat64.Dense, target [][]float64) (float64, error) {
+	errs, err := f.headErrors(output, target)
+	if err != nil {
+		return 0, err
+	}
+	var netError float64
+	for k, h := range f.heads {
+		netError += h.weight() * errs[k]
+	}
+	return netError, nil
+}
+
+// headErrors returns the error of every head
+func (f headsFunc) headErrors(output *mat64.Dense, target [][]float64) ([]float64, error) {
+	rows, _ := output.Dims()
+	if len(target) != rows {
+		return nil, &ShapeError{Layer: -1, Want: []int{rows}, Got: []int{len(target)}}
+	}
+	errs := make([]float64, len(f.heads))
+	headTarget := make([][]float64, rows)
+	for k, h := range f.heads {
+		s := f.starts[k]
+		for i := range target {
+			if len(target[i]) < s+h.Size {
+				return nil, &ShapeError{Layer: -1, Want: []int{rows, s + h.Size}, Got: []int{rows, len(target[i])}}
+			}
+			headTarget[i] = target[i][s : s+h.Size]
+		}
+		var err error
+		errs[k], err = f.losses[k](output.View(0, s, rows, h.Size).(*mat64.Dense), headTarget)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return errs, nil
+}
+
+// headsONNX splits the output layer, activates every head and concatenates them
+func headsONNX(b *onnxBuilder, n *Network, layer int, in string) string {
+	heads := n.Heads
+	if len(heads) == 1 {
+		return onnxActivationMap[heads[0].Activation](b, n, layer, in)
+	}
+	sizes := make([]int64, len(heads))
+	for k, h := range heads {
+		sizes[k] = int64(h.Size)
+	}
+	split := b.nodeOutputs("Split", []onnxAttribute{{Name: "axis", Type: onnxAttrInt, I: 1}}, len(heads), in, b.shape(b.prefix+".heads", sizes...))
+	activated := make([]string, len(split))
+	for k, h := range heads {
+		activated[k] = onnxActivationMap[h.Activation](b, n, layer, split[k])
+	}
+	return b.nodeAttr("Concat", []onnxAttribute{{Name: "axis", Type: onnxAttrInt, I: 1}}, activated...)
+}
+
+// HeadOutputs returns the output rows of every head keyed by the head name,
+// GetOutput returns the rows of the heads it is given
+func (n *Network) HeadOutputs() map[string][][]float64 {
+	return splitHeads(n.Heads, n.GetOutput())
+}
+
+// splitHeads splits the output rows in the rows of every head, keyed by the
+// head name. The head rows share their values with the output rows
+func splitHeads(heads []Head, output [][]float64) map[string][][]float64 {
+	byName := make(map[string][][]float64, len(heads))
+	start := 0
+	for _, h := range heads {
+		rows := make([][]float64, len(output))
+		for i, row := range output {
+			rows[i] = row[start : start+h.Size]
+		}
+		byName[h.Name] = rows
+		start += h.Size
+	}
+	return byName
+}
+
+// HeadTarget joins the target rows of every head, keyed by the head name,
+// in to the target rows of Backward and NetError
+func (n *Network) HeadTarget(targets map[string][][]float64) ([][]float64, error) {
+	if len(n.Heads) == 0 || len(targets) != len(n.Heads) {
+		return nil, ErrHeads
+	}
+	rows := len(targets[n.Heads[0].Name])
+	target := make([][]float64, rows)
+	for _, h := range n.Heads {
+		headTarget, ok := targets[h.Name]
+		if !ok || len(headTarget) != rows {
+			return nil, ErrHeads
+		}
+		for i, row := range headTarget {
+			if len(row) != h.Size {
+				return nil, &ShapeError{Layer: -1, Want: []int{rows, h.Size}, Got: []int{rows, len(row)}}
+			}
+			target[i] = append(target[i], row...)
+		}
+	}
+	return target, nil
+}
+
+// HeadErrors returns the error of every head keyed by the head name, before the loss weights
+func (n *Network) HeadErrors(target [][]float64) (map[string]float64, error) {
+	f, ok := n.Layers[n.OutputLayer].Activation.(*headsFunc)
+	if !ok {
+		return nil, ErrHeads
+	}
+	errs, err := f.headErrors(n.Layers[n.OutputLayer].Nodes, target)
+	if err != nil {
+		return nil, atLayer(err, n.OutputLayer)
+	}
+	heads := make(map[string]float64, len(errs))
+	for k, h := range f.heads {
+		heads[h.Name] = errs[k]
+	}
+	return heads, nil
+}
@@ -0,0 +1,242 @@
+package neuro
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+)
+
+var testHeads = []Head{
+	{Name: "color", Size: 3, Activation: "softmax"},
+	{Name: "size", Size: 5, Activation: "sigmoid", Loss: "mse", Weight: 2},
+	{Name: "flag", Size: 2, Activation: "softmax", Weight: 0.5},
+}
+
+// headsData is a network with the 10 output nodes of testHeads
+var headsData = NetData{
+	Nodes:       []int{3, 8, 10},
+	Activations: []string{"tanh", "heads"},
+	Heads:       testHeads,
+	BatchSize:   2,
+	Train:       true,
+	Seed:        5,
+}
+
+var headsTestInput = [][]float64{{0.5, -1, 2}, {1, 1, -3}}
+
+func headsTestTarget(t *testing.T, n *Network) [][]float64 {
+	target, err := n.HeadTarget(map[string][][]float64{
+		"color": {{1, 0, 0}, {0, 0, 1}},
+		"size":  {{0.1, 0.2, 0.3, 0.4, 0.5}, {0.9, 0.8, 0.7, 0.6, 0.5}},
+		"flag":  {{0, 1}, {1, 0}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return target
+}
+
+func TestHeadOutputs(t *testing.T) {
+	n := testNetwork(t, headsData)
+	if err := n.Forward(headsTestInput); err != nil {
+		t.Fatal(err)
+	}
+	outputs := n.HeadOutputs()
+	if len(outputs) != 3 || len(outputs["size"][1]) != 5 {
+		t.Fatalf("Unexpected head outputs %v", outputs)
+	}
+	for _, name := range []string{"color", "flag"} {
+		for _, row := range outputs[name] {
+			var sum float64
+			for _, v := range row {
+				sum += v
+			}
+			if math.Abs(sum-1) > 1e-12 {
+				t.Errorf("Softmax head %s sums to %v", name, sum)
+			}
+		}
+	}
+	if outputs["size"][0][0] != n.GetOutput()[0][3] {
+		t.Error("Head outputs do not match the output layer")
+	}
+	// GetOutput selects the heads by name, in the order of the names
+	got := n.GetOutput("flag", "color")
+	for i, row := range got {
+		if fmt.Sprint(row) != fmt.Sprint(append(append([]float64(nil), outputs["flag"][i]...), outputs["color"][i]...)) {
+			t.Errorf("Row %d of the flag and color heads: got %v", i, row)
+		}
+	}
+	if len(got) != n.BatchSize || n.GetOutput("shape") != nil {
+		t.Error("Expected the rows of the batch and nil for an unknown head")
+	}
+}
+
+func TestHeadsTraining(t *testing.T) {
+	n := testNetwork(t, headsData)
+	target := headsTestTarget(t, n)
+	var first, last float64
+	for i := 0; i < 200; i++ {
+		if err := n.Forward(headsTestInput); err != nil {
+			t.Fatal(err)
+		}
+		netError, err := n.NetError(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = netError
+		}
+		last = netError
+		if err := n.Backward(target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if last >= first {
+		t.Errorf("Training did not reduce the error: %v -> %v", first, last)
+	}
+	if err := n.Forward(headsTestInput); err != nil {
+		t.Fatal(err)
+	}
+	netError, err := n.NetError(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs, err := n.HeadErrors(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(errs["color"]+2*errs["size"]+0.5*errs["flag"]-netError) > 1e-9 {
+		t.Errorf("Head errors %v do not add up to the network error %v", errs, netError)
+	}
+}
+
+func TestHeadsWeight(t *testing.T) {
+	unweighted := make([]Head, len(testHeads))
+	copy(unweighted, testHeads)
+	for k := range unweighted {
+		unweighted[k].Weight = 0
+	}
+	data := headsData
+	data.Heads = unweighted
+	a, b := testNetwork(t, headsData), testNetwork(t, data)
+	for _, n := range []*Network{a, b} {
+		if err := n.Forward(headsTestInput); err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Backward(headsTestTarget(t, n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The output bias gradients of every head are scaled by its weight
+	start := 0
+	for _, h := range testHeads {
+		for r := start; r < start+h.Size; r++ {
+			got, want := a.Layers[1].DeltaBias.At(r, 0), h.weight()*b.Layers[1].DeltaBias.At(r, 0)
+			if math.Abs(got-want) > 1e-12 {
+				t.Errorf("Head %s node %d: bias gradient %v, want %v", h.Name, r, got, want)
+			}
+		}
+		start += h.Size
+	}
+}
+
+// The loss of a head selects its output error term
+func TestHeadsLoss(t *testing.T) {
+	for _, loss := range []string{"mse", "cross-entropy"} {
+		heads := make([]Head, len(testHeads))
+		copy(heads, testHeads)
+		heads[1].Loss = loss
+		data := headsData
+		data.Heads = heads
+		n := testNetwork(t, data)
+		target := headsTestTarget(t, n)
+		if err := n.Forward(headsTestInput); err != nil {
+			t.Fatal(err)
+		}
+		output := n.GetOutput()
+		if err := n.Backward(target); err != nil {
+			t.Fatal(err)
+		}
+		// The sigmoid "size" head holds the nodes 3 to 7 with a weight of 2
+		for r := 3; r < 8; r++ {
+			var want float64
+			for k := range target {
+				e := target[k][r] - output[k][r]
+				if loss == "mse" {
+					e *= output[k][r] * (1 - output[k][r])
+				}
+				want += 2 * e
+			}
+			if got := n.Layers[1].DeltaBias.At(r, 0); math.Abs(got-want) > 1e-12 {
+				t.Errorf("%s node %d: bias gradient %v, want %v", loss, r, got, want)
+			}
+		}
+	}
+}
+
+func TestHeadsModel(t *testing.T) {
+	n := testNetwork(t, headsData)
+	buf := &bytes.Buffer{}
+	if err := n.Encode(buf); err != nil {
+		t.Fatal(err)
+	}
+	y, err := Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(y.Heads) != 3 || y.Heads[1].Weight != 2 {
+		t.Fatalf("Heads were not restored: %v", y.Heads)
+	}
+	if err := n.Forward(headsTestInput); err != nil {
+		t.Fatal(err)
+	}
+	c, err := y.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Predict(headsTestInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, row := range n.GetOutput() {
+		for i, v := range row {
+			if math.Abs(got[k][i]-v) > 1e-12 {
+				t.Errorf("Compiled output [%d][%d]: got %v, want %v", k, i, got[k][i], v)
+			}
+		}
+	}
+	onnx := &bytes.Buffer{}
+	if err := n.EncodeONNX(onnx); err != nil {
+		t.Fatal(err)
+	}
+	m, err := parseONNX(onnx.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = evalONNX(m, headsTestInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, row := range n.GetOutput() {
+		for i, v := range row {
+			if math.Abs(got[k][i]-v) > 1e-12 {
+				t.Errorf("ONNX output [%d][%d]: got %v, want %v", k, i, got[k][i], v)
+			}
+		}
+	}
+}
+
+func TestHeadsErrors(t *testing.T) {
+	for _, data := range []NetData{
+		{Nodes: []int{2, 4}, Activations: []string{"heads"}, BatchSize: 1, Heads: []Head{{Name: "a", Size: 3, Activation: "sigmoid"}}},
+		{Nodes: []int{2, 4}, Activations: []string{"heads"}, BatchSize: 1, Heads: []Head{{Name: "a", Size: 2, Activation: "sigmoid"}, {Name: "a", Size: 2, Activation: "tanh"}}},
+		{Nodes: []int{2, 4}, Activations: []string{"heads"}, BatchSize: 1, Heads: []Head{{Name: "a", Size: 4, Activation: "sigmoid", Loss: "hinge"}}},
+		{Nodes: []int{2, 4}, Activations: []string{"sigmoid"}, BatchSize: 1, Heads: []Head{{Name: "a", Size: 4, Activation: "sigmoid"}}},
+		{Nodes: []int{2, 4, 2}, Activations: []string{"heads", "sigmoid"}, BatchSize: 1, Heads: []Head{{Name: "a", Size: 4, Activation: "sigmoid"}}},
+	} {
+		if _, err := New(data); err == nil {
+			t.Errorf("Expected an error for heads %v", data.Heads)
+		}
+	}
+}
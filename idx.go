@@ -0,0 +1,168 @@
+package neuro
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// The element types of IDX files by their type byte and the element sizes
+var idxSizes = map[byte]int{
+	0x08: 1, // unsigned byte
+	0x09: 1, // signed byte
+	0x0B: 2, // int16
+	0x0C: 4, // int32
+	0x0D: 4, // float32
+	0x0E: 8, // float64
+}
+
+// idxDecoders decode an element of every type, the numbers are big-endian
+var idxDecoders = map[byte]func([]byte) float64{
+	0x08: func(b []byte) float64 { return float64(b[0]) },
+	0x09: func(b []byte) float64 { return float64(int8(b[0])) },
+	0x0B: func(b []byte) float64 { return float64(int16(binary.BigEndian.Uint16(b))) },
+	0x0C: func(b []byte) float64 { return float64(int32(binary.BigEndian.Uint32(b))) },
+	0x0D: func(b []byte) float64 { return float64(math.Float32frombits(binary.BigEndian.Uint32(b))) },
+	0x0E: func(b []byte) float64 { return math.Float64frombits(binary.BigEndian.Uint64(b)) },
+}
+
+// maxIDXClasses is the largest number of classes counted from the labels
+const maxIDXClasses = 1 << 16
+
+// LoadIDX reads an IDX images file and its labels file, such as the MNIST
+// files, as a dataset. The images are flattened in to input rows and unsigned
+// byte pixels are scaled to [0, 1]. The labels are one-hot targets of classes,
+// 0 counts the classes from the largest label. Gzipped files are read as well
+func LoadIDX(images, labels string, classes int) (*MemoryDataset, error) {
+	in, err := readIDXFile(images, ReadIDXImages)
+	if err != nil {
+		return nil, err
+	}
+	target, err := readIDXFile(labels, func(r io.Reader) ([][]float64, error) {
+		return ReadIDXLabels(r, classes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(in) != len(target) {
+		return nil, fmt.Errorf("%w: %d images and %d labels", ErrIDXFormat, len(in), len(target))
+	}
+	return &MemoryDataset{Inputs: in, Targets: target}, nil
+}
+
+// readIDXFile opens a file, gzipped or not, and reads it with read
+func readIDXFile(path string, read func(io.Reader) ([][]float64, error)) ([][]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	br := bufio.NewReader(file)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return read(gz)
+	}
+	return read(br)
+}
+
+// ReadIDXImages reads an IDX file of images as one flattened row per image,
+// unsigned byte pixels are scaled to [0, 1]
+func ReadIDXImages(r io.Reader) ([][]float64, error) {
+	dims, kind, values, err := readIDX(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(dims) < 2 {
+		return nil, fmt.Errorf("%w: images need at least 2 dimensions, got %v", ErrIDXFormat, dims)
+	}
+	if kind == 0x08 {
+		for k := range values {
+			values[k] /= 255
+		}
+	}
+	if dims[0] == 0 {
+		return [][]float64{}, nil
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: images without pixels %v", ErrIDXFormat, dims)
+	}
+	rows := make([][]float64, dims[0])
+	width := len(values) / dims[0]
+	for k := range rows {
+		rows[k] = values[k*width : (k+1)*width : (k+1)*width]
+	}
+	return rows, nil
+}
+
+// ReadIDXLabels reads an IDX file of labels as one-hot rows of classes,
+// 0 counts the classes from the largest label
+func ReadIDXLabels(r io.Reader, classes int) ([][]float64, error) {
+	dims, _, values, err := readIDX(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(dims) != 1 {
+		return nil, fmt.Errorf("%w: labels need 1 dimension, got %v", ErrIDXFormat, dims)
+	}
+	if classes == 0 {
+		for _, v := range values {
+			if v >= maxIDXClasses {
+				return nil, fmt.Errorf("%w: label %v is over the %d classes limit", ErrIDXFormat, v, maxIDXClasses)
+			}
+			if int(v) >= classes {
+				classes = int(v) + 1
+			}
+		}
+	}
+	if len(values) > 0 && classes > maxReadValues/len(values) {
+		return nil, fmt.Errorf("%w: %d labels of %d classes are too large", ErrIDXFormat, len(values), classes)
+	}
+	rows := make([][]float64, len(values))
+	for k, v := range values {
+		if v < 0 || int(v) >= classes || v != math.Trunc(v) {
+			return nil, fmt.Errorf("%w: label %v of %d classes", ErrIDXFormat, v, classes)
+		}
+		rows[k] = make([]float64, classes)
+		rows[k][int(v)] = 1
+	}
+	return rows, nil
+}
+
+// readIDX reads the dimensions, the element type and the values of an IDX file
+func readIDX(r io.Reader) ([]int, byte, []float64, error) {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, 0, nil, err
+	}
+	size, ok := idxSizes[magic[2]]
+	if magic[0] != 0 || magic[1] != 0 || !ok || magic[3] == 0 {
+		return nil, 0, nil, ErrIDXFormat
+	}
+	dims := make([]int, magic[3])
+	count := 1
+	for k := range dims {
+		var dim uint32
+		if err := binary.Read(r, binary.BigEndian, &dim); err != nil {
+			return nil, 0, nil, err
+		}
+		dims[k] = int(dim)
+		// Checking every product keeps count from overflowing
+		if dims[k] > 0 && count > maxReadValues/dims[k] {
+			return nil, 0, nil, fmt.Errorf("%w: %v values are too large", ErrIDXFormat, dims[:k+1])
+		}
+		count *= dims[k]
+	}
+	values, err := readChunked(r, count, size, idxDecoders[magic[2]])
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("%w: %v", ErrIDXFormat, err)
+	}
+	return dims, magic[2], values, nil
+}
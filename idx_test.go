@@ -0,0 +1,109 @@
+package neuro
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	idxImages = "testdata/mnist/images-idx3-ubyte"
+	idxLabels = "testdata/mnist/labels-idx1-ubyte"
+)
+
+func TestLoadIDX(t *testing.T) {
+	d, err := LoadIDX(idxImages, idxLabels, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != 6 || len(d.Inputs[0]) != 6 || len(d.Targets[0]) != 3 {
+		t.Fatalf("Unexpected dataset of %d samples, %v", d.Len(), d.Inputs)
+	}
+	if d.Inputs[1][1] != 1 || d.Inputs[0][3] != 128.0/255 || d.Targets[2][2] != 1 {
+		t.Errorf("Unexpected sample %v %v", d.Inputs[0], d.Targets[2])
+	}
+
+	// Gzipped files and a fixed number of classes
+	data, err := ioutil.ReadFile(idxLabels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	gz.Write(data)
+	gz.Close()
+	path := filepath.Join(t.TempDir(), "labels-idx1-ubyte.gz")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err = LoadIDX(idxImages, path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Targets[0]) != 10 || d.Targets[4][1] != 1 {
+		t.Errorf("Unexpected targets %v", d.Targets)
+	}
+	if _, err := LoadIDX(idxImages, idxLabels, 2); !errors.Is(err, ErrIDXFormat) {
+		t.Errorf("Expected ErrIDXFormat for a label out of the classes, got %v", err)
+	}
+	if _, err := ReadIDXImages(bytes.NewReader([]byte{0, 0, 0x42, 1, 0, 0, 0, 0})); !errors.Is(err, ErrIDXFormat) {
+		t.Errorf("Expected ErrIDXFormat for an unknown type, got %v", err)
+	}
+}
+
+func TestIDXLimits(t *testing.T) {
+	for _, header := range [][]byte{
+		// 2^32-1 images of 2^32-1 pixels, only a few bytes follow
+		{0, 0, 0x08, 2, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 1, 2, 3},
+		// 2^31 images of 0 pixels
+		{0, 0, 0x08, 2, 0x80, 0, 0, 0, 0, 0, 0, 0},
+		// 4096 images of 4096 pixels, truncated
+		{0, 0, 0x08, 2, 0, 0, 0x10, 0, 0, 0, 0x10, 0, 1, 2, 3},
+	} {
+		if _, err := ReadIDXImages(bytes.NewReader(header)); !errors.Is(err, ErrIDXFormat) {
+			t.Errorf("Header %v: expected ErrIDXFormat, got %v", header[:12], err)
+		}
+	}
+	// A single int32 label of 2^31-1 counted as the number of classes
+	labels := []byte{0, 0, 0x0C, 1, 0, 0, 0, 1, 0x7f, 0xff, 0xff, 0xff}
+	if _, err := ReadIDXLabels(bytes.NewReader(labels), 0); !errors.Is(err, ErrIDXFormat) {
+		t.Errorf("Expected ErrIDXFormat for a huge label, got %v", err)
+	}
+}
+
+func TestIDXClassifier(t *testing.T) {
+	d, err := LoadIDX(idxImages, idxLabels, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := New(NetData{Nodes: []int{6, 8, 3}, Activations: []string{"tanh", "softmax"}, BatchSize: 6, Train: true, Seed: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.LearnRate = 0.5
+	for i := 0; i < 300; i++ {
+		if err := n.Forward(d.Inputs); err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Backward(d.Targets); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := n.Forward(d.Inputs); err != nil {
+		t.Fatal(err)
+	}
+	for k, row := range n.GetOutput() {
+		best := 0
+		for c := range row {
+			if row[c] > row[best] {
+				best = c
+			}
+		}
+		if d.Targets[k][best] != 1 {
+			t.Errorf("Sample %d classified as %d, outputs %v", k, best, row)
+		}
+	}
+}
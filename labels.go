@@ -0,0 +1,148 @@
+package neuro
+
+import (
+	"fmt"
+	"sort"
+)
+
+type (
+	// LabelEncoder maps the string labels of a classifier to one-hot target rows and back
+	LabelEncoder struct {
+		Labels []string
+		index  map[string]int
+	}
+	// ClassProbability is the output probability of a class
+	ClassProbability struct {
+		Label       string
+		Probability float64
+	}
+)
+
+// NewLabelEncoder returns the encoder of labels, in the order of the output nodes
+func NewLabelEncoder(labels ...string) (*LabelEncoder, error) {
+	e := &LabelEncoder{Labels: labels, index: make(map[string]int, len(labels))}
+	for k, label := range labels {
+		if _, ok := e.index[label]; ok {
+			return nil, fmt.Errorf("%w: duplicate label %q", ErrLabels, label)
+		}
+		e.index[label] = k
+	}
+	if len(labels) == 0 {
+		return nil, ErrLabels
+	}
+	return e, nil
+}
+
+// FitLabels returns the encoder of the distinct labels of a training set, sorted
+func FitLabels(labels []string) (*LabelEncoder, error) {
+	seen := map[string]bool{}
+	var distinct []string
+	for _, label := range labels {
+		if !seen[label] {
+			seen[label] = true
+			distinct = append(distinct, label)
+		}
+	}
+	sort.Strings(distinct)
+	return NewLabelEncoder(distinct...)
+}
+
+// Encode returns the one-hot target rows of labels
+func (e *LabelEncoder) Encode(labels []string) ([][]float64, error) {
+	rows := make([][]float64, len(labels))
+	for k, label := range labels {
+		class, ok := e.index[label]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown label %q", ErrLabels, label)
+		}
+		rows[k] = make([]float64, len(e.Labels))
+		rows[k][class] = 1
+	}
+	return rows, nil
+}
+
+// Decode returns the label of the largest value of every row, the rows
+// must hold a value per label
+func (e *LabelEncoder) Decode(rows [][]float64) ([]string, error) {
+	labels := make([]string, len(rows))
+	for k, row := range rows {
+		if len(row) != len(e.Labels) {
+			return nil, &ShapeError{Err: ErrLabels, Layer: -1, Want: []int{len(e.Labels)}, Got: []int{len(row)}}
+		}
+		labels[k] = e.Labels[argmax(row)]
+	}
+	return labels, nil
+}
+
+// LabelEncoder returns the encoder of the network's Labels, nil without labels
+func (n *Network) LabelEncoder() *LabelEncoder {
+	e, err := NewLabelEncoder(n.Labels...)
+	if err != nil {
+		return nil
+	}
+	return e
+}
+
+// PredictClass passes the inputs through the network and returns the label
+// of the most probable class of every input row
+func (n *Network) PredictClass(in [][]float64) ([]string, error) {
+	output, err := n.predict(in)
+	if err != nil {
+		return nil, err
+	}
+	labels := make([]string, len(output))
+	for k, row := range output {
+		labels[k] = n.Labels[argmax(row)]
+	}
+	return labels, nil
+}
+
+// PredictProba passes the inputs through the network and returns the k most
+// probable classes of every input row, most probable first. With k < 1 all the classes are returned
+func (n *Network) PredictProba(in [][]float64, k int) ([][]ClassProbability, error) {
+	output, err := n.predict(in)
+	if err != nil {
+		return nil, err
+	}
+	if k < 1 || k > len(n.Labels) {
+		k = len(n.Labels)
+	}
+	classes := make([][]ClassProbability, len(output))
+	for i, row := range output {
+		probabilities := make([]ClassProbability, len(row))
+		for c, v := range row {
+			probabilities[c] = ClassProbability{n.Labels[c], v}
+		}
+		sort.SliceStable(probabilities, func(a, b int) bool {
+			return probabilities[a].Probability > probabilities[b].Probability
+		})
+		classes[i] = probabilities[:k]
+	}
+	return classes, nil
+}
+
+// predict runs Forward and returns the output rows of the inputs. The
+// Labels can be changed after New, so their count is checked again
+func (n *Network) predict(in [][]float64) ([][]float64, error) {
+	if len(n.Labels) == 0 {
+		return nil, ErrLabels
+	}
+	if outputs := n.Layers[n.OutputLayer].NodesCount; len(n.Labels) != outputs {
+		return nil, &ShapeError{Err: ErrLabels, Layer: n.OutputLayer, Want: []int{outputs}, Got: []int{len(n.Labels)}}
+	}
+	if err := n.Forward(in); err != nil {
+		return nil, err
+	}
+	return n.GetOutput()[:len(in)], nil
+}
+
+// argmax returns the index of the largest value
+func argmax(row []float64) int {
+	best := 0
+	for k, v := range row {
+		if v > row[best] {
+			best = k
+		}
+	}
+	return best
+}
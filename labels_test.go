@@ -0,0 +1,108 @@
+package neuro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestLabelEncoder(t *testing.T) {
+	e, err := FitLabels([]string{"dog", "cat", "dog", "bird"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(e.Labels) != "[bird cat dog]" {
+		t.Errorf("Unexpected labels %v", e.Labels)
+	}
+	rows, err := e.Encode([]string{"cat", "dog"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(rows) != "[[0 1 0] [0 0 1]]" {
+		t.Errorf("Unexpected one-hot rows %v", rows)
+	}
+	labels, err := e.Decode([][]float64{{0.1, 0.2, 0.7}, {0.6, 0.3, 0.1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(labels) != "[dog bird]" {
+		t.Errorf("Unexpected decoded labels %v", labels)
+	}
+	if _, err := e.Decode([][]float64{{0.1, 0.2, 0.3, 0.9}}); !errors.Is(err, ErrLabels) {
+		t.Errorf("Expected ErrLabels for a row wider than the labels, got %v", err)
+	}
+	if _, err := e.Encode([]string{"fish"}); !errors.Is(err, ErrLabels) {
+		t.Errorf("Expected ErrLabels for an unknown label, got %v", err)
+	}
+	if _, err := NewLabelEncoder("a", "b", "a"); !errors.Is(err, ErrLabels) {
+		t.Errorf("Expected ErrLabels for a duplicate label, got %v", err)
+	}
+}
+
+func TestPredictClass(t *testing.T) {
+	labels := []string{"low", "mid", "high"}
+	n, err := New(NetData{Nodes: []int{1, 6, 3}, Activations: []string{"tanh", "softmax"}, BatchSize: 3, Train: true, Labels: labels, Seed: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.LearnRate = 0.3
+	in := [][]float64{{-1}, {0}, {1}}
+	target, err := n.LabelEncoder().Encode([]string{"low", "mid", "high"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 500; i++ {
+		if err := n.Forward(in); err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Backward(target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf := &bytes.Buffer{}
+	n.Format = "binary"
+	if err := n.Encode(buf); err != nil {
+		t.Fatal(err)
+	}
+	y, err := Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	classes, err := y.PredictClass(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(classes) != "[low mid high]" {
+		t.Errorf("Predicted classes %v, want [low mid high]", classes)
+	}
+	proba, err := y.PredictProba(in[2:], 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proba) != 1 || len(proba[0]) != 2 || proba[0][0].Label != "high" || proba[0][0].Probability < proba[0][1].Probability {
+		t.Errorf("Unexpected top 2 classes %v", proba)
+	}
+	if all, _ := y.PredictProba(in, 0); len(all[0]) != 3 {
+		t.Errorf("Expected all the classes with k = 0, got %v", all[0])
+	}
+
+	if _, err := New(NetData{Nodes: []int{1, 2}, Activations: []string{"softmax"}, BatchSize: 1, Labels: labels}); !errors.Is(err, ErrLabels) {
+		t.Errorf("Expected ErrLabels for 3 labels of 2 outputs, got %v", err)
+	}
+	plain, err := New(NetData{Nodes: []int{1, 2}, Activations: []string{"softmax"}, BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plain.PredictClass([][]float64{{1}}); !errors.Is(err, ErrLabels) {
+		t.Errorf("Expected ErrLabels without labels, got %v", err)
+	}
+	// Labels set after New must still match the output layer
+	plain.Labels = []string{"one"}
+	if _, err := plain.PredictClass([][]float64{{1}}); !errors.Is(err, ErrLabels) {
+		t.Errorf("PredictClass: expected ErrLabels for 1 label of 2 outputs, got %v", err)
+	}
+	if _, err := plain.PredictProba([][]float64{{1}}, 0); !errors.Is(err, ErrLabels) {
+		t.Errorf("PredictProba: expected ErrLabels for 1 label of 2 outputs, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package neuro
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// LIBSVMOptions configures the reading of LIBSVM files
+	LIBSVMOptions struct {
+		// Features is the width of the input rows, 0 uses the largest feature index of the file
+		// as long as the input rows hold at most 1<<28 values in all
+		Features int
+		// OneHot turns the labels in to one-hot targets, the classes follow the
+		// ascending label values. Otherwise the target is the label itself
+		OneHot bool
+		// Classes lists the labels of the one-hot classes in order, by default
+		// the labels found in the file
+		Classes []float64
+	}
+	// libsvmFeature is a value of a sparse LIBSVM sample, the index starts at 1
+	libsvmFeature struct {
+		index int
+		value float64
+	}
+	// libsvmReader reads the samples of LIBSVM lines one by one
+	libsvmReader struct {
+		scanner *bufio.Scanner
+		opts    LIBSVMOptions
+		classes map[float64]int
+		line    int
+	}
+)
+
+// LoadLIBSVM reads a dataset from a file in the sparse LIBSVM format
+func LoadLIBSVM(path string, opts LIBSVMOptions) (*MemoryDataset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadLIBSVM(file, opts)
+}
+
+// ReadLIBSVM reads a dataset of "label index:value ..." lines, the feature
+// indexes start at 1 and missing features are 0. Comments start with '#'
+func ReadLIBSVM(r io.Reader, opts LIBSVMOptions) (*MemoryDataset, error) {
+	var (
+		labels   []float64
+		samples  [][]libsvmFeature
+		features = opts.Features
+	)
+	lr := newLIBSVMReader(r, opts)
+	for {
+		label, sample, err := lr.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := lr.classes[label]; opts.OneHot && opts.Classes != nil && !ok {
+			return nil, fmt.Errorf("%w: line %d: unknown class %v", ErrDataset, lr.line, label)
+		}
+		for _, f := range sample {
+			if f.index > features {
+				features = f.index
+			}
+		}
+		labels = append(labels, label)
+		samples = append(samples, sample)
+	}
+	// The dense rows of the inferred width must fit in the values limit
+	if opts.Features == 0 && len(samples) > 0 && features > maxReadValues/len(samples) {
+		return nil, fmt.Errorf("%w: %d rows of %d features are too large", ErrDataset, len(samples), features)
+	}
+	if opts.OneHot && opts.Classes == nil {
+		found := map[float64]bool{}
+		for _, label := range labels {
+			if !found[label] {
+				found[label] = true
+				opts.Classes = append(opts.Classes, label)
+			}
+		}
+		sort.Float64s(opts.Classes)
+	}
+	opts.Features = features
+	lr = newLIBSVMReader(nil, opts)
+	d := &MemoryDataset{Inputs: make([][]float64, len(samples)), Targets: make([][]float64, len(samples))}
+	for k, sample := range samples {
+		var err error
+		if d.Inputs[k], d.Targets[k], err = lr.sample(labels[k], sample); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// NewLIBSVMReader returns a SampleReader of LIBSVM lines, see ReadLIBSVM.
+// The options need the Features, and the Classes with OneHot, since the
+// samples are read one by one
+func NewLIBSVMReader(r io.Reader, opts LIBSVMOptions) (SampleReader, error) {
+	if opts.Features < 1 || opts.OneHot && len(opts.Classes) == 0 {
+		return nil, fmt.Errorf("%w: the LIBSVM reader needs the features and the one-hot classes", ErrDataset)
+	}
+	return newLIBSVMReader(r, opts), nil
+}
+
+// LIBSVMDecoder returns the decoder of LIBSVM streams
+func LIBSVMDecoder(opts LIBSVMOptions) func(io.Reader) (SampleReader, error) {
+	return func(r io.Reader) (SampleReader, error) {
+		return NewLIBSVMReader(r, opts)
+	}
+}
+
+func newLIBSVMReader(r io.Reader, opts LIBSVMOptions) *libsvmReader {
+	lr := &libsvmReader{opts: opts, classes: map[float64]int{}}
+	if r != nil {
+		lr.scanner = bufio.NewScanner(r)
+		lr.scanner.Buffer(nil, 1<<24)
+	}
+	for k, label := range opts.Classes {
+		lr.classes[label] = k
+	}
+	return lr
+}
+
+// Read returns the input and the target of the next line, io.EOF at the end
+func (lr *libsvmReader) Read() ([]float64, []float64, error) {
+	label, sample, err := lr.next()
+	if err != nil {
+		return nil, nil, err
+	}
+	return lr.sample(label, sample)
+}
+
+// next parses the next line holding a sample
+func (lr *libsvmReader) next() (float64, []libsvmFeature, error) {
+	for lr.scanner.Scan() {
+		lr.line++
+		text := lr.scanner.Text()
+		if k := strings.IndexByte(text, '#'); k >= 0 {
+			text = text[:k]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		label, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%w: line %d: %v", ErrDataset, lr.line, err)
+		}
+		sample := make([]libsvmFeature, 0, len(fields)-1)
+		for _, field := range fields[1:] {
+			colon := strings.IndexByte(field, ':')
+			if colon < 0 {
+				return 0, nil, fmt.Errorf("%w: line %d: %q is not index:value", ErrDataset, lr.line, field)
+			}
+			index, err := strconv.Atoi(field[:colon])
+			if err != nil || index < 1 || lr.opts.Features > 0 && index > lr.opts.Features {
+				return 0, nil, fmt.Errorf("%w: line %d: invalid feature index %q", ErrDataset, lr.line, field[:colon])
+			}
+			value, err := strconv.ParseFloat(field[colon+1:], 64)
+			if err != nil {
+				return 0, nil, fmt.Errorf("%w: line %d: %v", ErrDataset, lr.line, err)
+			}
+			sample = append(sample, libsvmFeature{index, value})
+		}
+		return label, sample, nil
+	}
+	if err := lr.scanner.Err(); err != nil {
+		return 0, nil, err
+	}
+	return 0, nil, io.EOF
+}
+
+// sample returns the dense input and the target of a parsed line
+func (lr *libsvmReader) sample(label float64, sample []libsvmFeature) ([]float64, []float64, error) {
+	in := make([]float64, lr.opts.Features)
+	for _, f := range sample {
+		in[f.index-1] = f.value
+	}
+	if !lr.opts.OneHot {
+		return in, []float64{label}, nil
+	}
+	class, ok := lr.classes[label]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: line %d: unknown class %v", ErrDataset, lr.line, label)
+	}
+	target := make([]float64, len(lr.opts.Classes))
+	target[class] = 1
+	return in, target, nil
+}
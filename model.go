@@ -0,0 +1,221 @@
+package neuro
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+type (
+	// Model is the versioned file format of an exported network.
+	// Files written before the format was versioned hold a bare NetData
+	// and are read as format version 0.
+	Model struct {
+		FormatVersion  int
+		LibraryVersion string
+		Loss           string
+		// The training settings of the network, restored when the model is read
+		LearnRate  float64 `json:",omitempty"`
+		Momentum   float64 `json:",omitempty"`
+		ClipValue  float64 `json:",omitempty"`
+		ClipNorm   float64 `json:",omitempty"`
+		AccumSteps int     `json:",omitempty"`
+		NetData
+	}
+	// Normalization standardizes every input value as (v - Mean) / Std
+	Normalization struct {
+		Mean []float64
+		Std  []float64
+	}
+)
+
+const (
+	// Version is the library version written in to exported models
+	Version = "0.3.0"
+	// FormatVersion is the current version of the model file format,
+	// version 2 added the per-layer SoftmaxGroups
+	FormatVersion = 2
+)
+
+// model wraps the network data in the current model format
+func (n *Network) model(data NetData) Model {
+	return Model{
+		FormatVersion:  FormatVersion,
+		LibraryVersion: Version,
+		Loss:           n.Layers[n.OutputLayer].Activation.loss(),
+		LearnRate:      n.LearnRate,
+		Momentum:       n.Momentum,
+		ClipValue:      n.ClipValue,
+		ClipNorm:       n.ClipNorm,
+		AccumSteps:     n.AccumSteps,
+		NetData:        data,
+	}
+}
+
+// restore sets the training settings stored in the model on the network
+func (model *Model) restore(n *Network) {
+	n.LearnRate = model.LearnRate
+	n.Momentum = model.Momentum
+	n.ClipValue = model.ClipValue
+	n.ClipNorm = model.ClipNorm
+	n.AccumSteps = model.AccumSteps
+}
+
+// Encode writes the network as a model to w, as JSON or binary depending on the network's Format
+func (n *Network) Encode(w io.Writer) error {
+	data, err := n.Export("")
+	if err != nil {
+		return err
+	}
+	return n.encode(w, data)
+}
+
+// Decode reads a JSON or binary model from r and returns its network,
+// using the batch size and training mode stored in the model
+func Decode(r io.Reader) (*Network, error) {
+	model, err := readModel(r)
+	if err != nil {
+		return nil, err
+	}
+	n, err := New(model.NetData)
+	if err != nil {
+		return nil, err
+	}
+	model.restore(n)
+	return n, nil
+}
+
+func (n *Network) encode(w io.Writer, data NetData) error {
+	switch n.Format {
+	case "", "json":
+		js, err := json.Marshal(n.model(data))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(js)
+		return err
+	case "binary":
+		_, err := n.WriteTo(w)
+		return err
+	}
+	return ErrUnknownFormat
+}
+
+// readModel reads a JSON or binary model, validates it and migrates it to the current format
+func readModel(r io.Reader) (Model, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(binaryMagic)); len(magic) > 0 && isBinaryModel(magic) {
+		return readBinary(br)
+	}
+	model := Model{}
+	if err := json.NewDecoder(br).Decode(&model); err != nil {
+		return Model{}, err
+	}
+	if err := model.validate(); err != nil {
+		return Model{}, err
+	}
+	return model, nil
+}
+
+// writeFileAtomic writes a file through a temporary file in the same directory
+// that is renamed over path, so readers never see a partially written file
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	// Removing fails harmlessly once the file was renamed
+	defer os.Remove(tmp.Name())
+	bw := bufio.NewWriter(tmp)
+	if err := write(bw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// validate checks the model description and migrates it to the current format
+func (model *Model) validate() error {
+	if model.FormatVersion < 0 || model.FormatVersion > FormatVersion {
+		return ErrFormatVersion
+	}
+	if model.FormatVersion == 0 {
+		migrateModelV0(model)
+	}
+	if len(model.Nodes) < 2 {
+		return ErrLayersCount
+	}
+	if len(model.Nodes)-1 != len(model.Activations) {
+		return ErrActivationCount
+	}
+	act, ok := activationMap[model.Activations[len(model.Activations)-1]]
+	if !ok {
+		return ErrUnknownActivation
+	}
+	if model.Loss != act.loss() {
+		return ErrModelLoss
+	}
+	return nil
+}
+
+// migrateModelV0 upgrades a bare NetData export. Those exports did not
+// store the loss, and a zero SplitSoftmax already keeps every softmax layer
+// as one group.
+func migrateModelV0(model *Model) {
+	if len(model.Activations) > 0 {
+		if act, ok := activationMap[model.Activations[len(model.Activations)-1]]; ok {
+			model.Loss = act.loss()
+		}
+	}
+	model.FormatVersion = FormatVersion
+}
+
+// apply normalizes the values in place
+func (norm *Normalization) apply(a []float64) {
+	for k := range a {
+		if norm.Std[k] == 0 {
+			a[k] -= norm.Mean[k]
+			continue
+		}
+		a[k] = (a[k] - norm.Mean[k]) / norm.Std[k]
+	}
+}
+
+// apply32 normalizes float32 values in place
+func (norm *Normalization) apply32(a []float32) {
+	for k := range a {
+		if norm.Std[k] == 0 {
+			a[k] -= float32(norm.Mean[k])
+			continue
+		}
+		a[k] = float32((float64(a[k]) - norm.Mean[k]) / norm.Std[k])
+	}
+}
+
+// clone returns a copy of the normalization values
+func (norm *Normalization) clone() *Normalization {
+	if norm == nil {
+		return nil
+	}
+	return &Normalization{
+		Mean: append([]float64(nil), norm.Mean...),
+		Std:  append([]float64(nil), norm.Std...),
+	}
+}
@@ -0,0 +1,335 @@
+package neuro
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestModelFormat(t *testing.T) {
+	n, err := New(NetData{
+		Nodes:        []int{3, 5, 4},
+		Activations:  []string{"tanh", "softmax"},
+		BatchSize:    2,
+		SplitSoftmax: 2,
+		Metadata:     map[string]string{"dataset": "test"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "model.json")
+	if _, err := n.Export(path); err != nil {
+		t.Fatal(err)
+	}
+	js, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := Model{}
+	if err := json.Unmarshal(js, &model); err != nil {
+		t.Fatal(err)
+	}
+	if model.FormatVersion != FormatVersion || model.LibraryVersion != Version {
+		t.Errorf("Unexpected versions %d %q", model.FormatVersion, model.LibraryVersion)
+	}
+	if model.Loss != "cross-entropy" || model.SplitSoftmax != 2 || model.BatchSize != 2 {
+		t.Errorf("Unexpected model fields %+v", model)
+	}
+	// The stored batch size is used when none is given
+	y, err := Import(path, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y.BatchSize != 2 || y.Metadata["dataset"] != "test" {
+		t.Errorf("Imported batch size %d, metadata %v", y.BatchSize, y.Metadata)
+	}
+	// Editing an export leaves the network metadata unchanged
+	data, err := n.Export("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Metadata["dataset"] = "changed"
+	if n.Metadata["dataset"] != "test" {
+		t.Errorf("Export shares the metadata with the network: %v", n.Metadata)
+	}
+
+	model.FormatVersion = FormatVersion + 1
+	if err := writeJSON(path, model); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(path, 0, false); err == nil {
+		t.Error("Expected an error for a newer format version")
+	}
+	model.FormatVersion = FormatVersion
+	model.Loss = "mse"
+	if err := writeJSON(path, model); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(path, 0, false); err == nil {
+		t.Error("Expected an error for a loss mismatch")
+	}
+}
+
+func TestModelMigrateV0(t *testing.T) {
+	n, err := New(NetData{
+		Nodes:        []int{3, 5, 4},
+		Activations:  []string{"tanh", "softmax"},
+		BatchSize:    1,
+		SplitSoftmax: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := n.Export("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unversioned exports left the softmax grouping empty
+	data.SplitSoftmax = 0
+	path := filepath.Join(t.TempDir(), "legacy.json")
+	if err := writeJSON(path, data); err != nil {
+		t.Fatal(err)
+	}
+	y, err := Import(path, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y.SplitSoftmax != 0 || y.SoftmaxGroups[1] != nil {
+		t.Errorf("Migrated softmax grouping is %d %v, want the whole layer", y.SplitSoftmax, y.SoftmaxGroups[1])
+	}
+	if err := y.Forward([][]float64{[]float64{1, 0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	var sum float64
+	for _, v := range y.GetOutput()[0] {
+		sum += v
+	}
+	if math.Abs(sum-1) > 1e-12 {
+		t.Errorf("Softmax output sums to %v", sum)
+	}
+}
+
+// A hidden softmax layer of a different width than the output layer is one group
+func TestModelV0HiddenSoftmax(t *testing.T) {
+	n, err := Import(filepath.Join("testdata", "model_v0.json"), 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Forward([][]float64{{1, -2}}); err != nil {
+		t.Fatal(err)
+	}
+	for k, l := range n.Layers {
+		var sum float64
+		for _, v := range l.Nodes.RawRowView(0) {
+			sum += v
+		}
+		if math.Abs(sum-1) > 1e-12 {
+			t.Errorf("Softmax layer %d sums to %v", k, sum)
+		}
+	}
+}
+
+func TestNormalization(t *testing.T) {
+	data := NetData{
+		Nodes:       []int{2, 3, 1},
+		Activations: []string{"tanh", "sigmoid"},
+		BatchSize:   1,
+	}
+	n, err := New(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.WeightsData = exportWeights(t, n)
+	data.Normalization = &Normalization{Mean: []float64{1, 2}, Std: []float64{2, 0}}
+	y, err := New(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Forward([][]float64{[]float64{1, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := y.Forward([][]float64{[]float64{3, 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := n.GetOutput()[0][0], y.GetOutput()[0][0]; math.Abs(want-got) > 1e-12 {
+		t.Errorf("Normalized output is %v, want %v", got, want)
+	}
+	data.Normalization = &Normalization{Mean: []float64{1}, Std: []float64{1}}
+	if _, err := New(data); err == nil {
+		t.Error("Expected an error for normalization values of the wrong size")
+	}
+}
+
+// The inference engines and the trainer replicas normalize the inputs like Forward
+func TestNormalizationEngines(t *testing.T) {
+	n, err := New(NetData{
+		Nodes:         []int{2, 3, 1},
+		Activations:   []string{"tanh", "sigmoid"},
+		BatchSize:     1,
+		Train:         true,
+		Normalization: &Normalization{Mean: []float64{10, 10}, Std: []float64{5, 5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := [][]float64{[]float64{20, 0}}
+	if err := n.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	want := n.GetOutput()[0][0]
+	c, err := n.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Predict(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got[0][0]-want) > 1e-12 {
+		t.Errorf("Compiled output is %v, want %v", got[0][0], want)
+	}
+	n32, err := n.Float32()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n32.Forward([][]float32{[]float32{20, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := float64(n32.GetOutput()[0][0]); math.Abs(got-want) > 1e-6 {
+		t.Errorf("Float32 output is %v, want %v", got, want)
+	}
+	// The engines keep their own copy of the values
+	n.Normalization.Mean[0] = 0
+	if got, _ := c.Predict(in); math.Abs(got[0][0]-want) > 1e-12 {
+		t.Errorf("Compiled output changed with the network to %v", got[0][0])
+	}
+	parallel, err := NewParallelTrainer(n, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parallel.replicas[0].Normalization == nil {
+		t.Error("The parallel trainer replicas do not normalize the inputs")
+	}
+}
+
+func TestModelTrainingSettings(t *testing.T) {
+	n, err := New(NetData{
+		Nodes:         []int{2, 3, 1},
+		Activations:   []string{"tanh", "sigmoid"},
+		BatchSize:     1,
+		Train:         true,
+		Normalization: &Normalization{Mean: []float64{1, 2}, Std: []float64{2, 1}},
+		Preprocessing: Pipeline{{Kind: "minmax", Inputs: 2, Center: []float64{0, 0}, Scale: []float64{1, 1}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.LearnRate, n.Momentum, n.ClipValue, n.ClipNorm, n.AccumSteps = 0.1, 0.9, 5, 10, 4
+	for _, format := range []string{"json", "binary"} {
+		n.Format = format
+		buf := &bytes.Buffer{}
+		if err := n.Encode(buf); err != nil {
+			t.Fatal(err)
+		}
+		y, err := Decode(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if y.LearnRate != 0.1 || y.Momentum != 0.9 || y.ClipValue != 5 || y.ClipNorm != 10 || y.AccumSteps != 4 {
+			t.Errorf("%s model: training settings were not restored: %v %v %v %v %v", format, y.LearnRate, y.Momentum, y.ClipValue, y.ClipNorm, y.AccumSteps)
+		}
+	}
+	// The exported data does not share its values with the network
+	data := n.netData()
+	data.Normalization.Mean[0] = 100
+	data.Preprocessing[0].Scale[0] = 100
+	if n.Normalization.Mean[0] != 1 || n.Preprocessing[0].Scale[0] != 1 {
+		t.Error("Changing the exported data changed the network")
+	}
+}
+
+func writeJSON(path string, v interface{}) error {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, js, 0644)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestEncodeDecode(t *testing.T) {
+	for _, format := range []string{"json", "binary"} {
+		n, err := New(NetData{
+			Nodes:        []int{3, 5, 4},
+			Activations:  []string{"tanh", "softmax"},
+			BatchSize:    2,
+			SplitSoftmax: 2,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.Format = format
+		buf := &bytes.Buffer{}
+		if err := n.Encode(buf); err != nil {
+			t.Fatal(err)
+		}
+		y, err := Decode(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if y.BatchSize != 2 {
+			t.Errorf("%s: decoded batch size %d, want 2", format, y.BatchSize)
+		}
+		assertSameWeights(t, exportWeights(t, n), exportWeights(t, y))
+		if err := n.Encode(failingWriter{}); err == nil {
+			t.Errorf("%s: expected the write error to be returned", format)
+		}
+	}
+}
+
+func TestExportAtomic(t *testing.T) {
+	n, err := New(NetData{
+		Nodes:       []int{3, 5, 2},
+		Activations: []string{"tanh", "sigmoid"},
+		BatchSize:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.json")
+	if err := ioutil.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Export(path); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Expected only the model file, found %d files", len(files))
+	}
+	if _, err := Import(path, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	// A failed export leaves the existing file untouched
+	n.Format = "yaml"
+	if _, err := n.Export(path); err == nil {
+		t.Fatal("Expected an error for an unknown format")
+	}
+	if _, err := Import(path, 1, false); err != nil {
+		t.Errorf("Existing model was damaged: %v", err)
+	}
+	if _, err := n.Export(filepath.Join(dir, "missing", "model.json")); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
@@ -1,10 +1,8 @@
 package neuro
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"math/rand"
 	"os"
 	"time"
@@ -24,6 +22,41 @@ type (
 		Target      [][]float64
 		LearnRate   float64
 		Momentum    float64
+		// ClipValue limits every gradient element to [-ClipValue, ClipValue], 0 disables it
+		ClipValue float64
+		// ClipNorm rescales the gradients when their global L2 norm exceeds it, 0 disables it
+		ClipNorm float64
+		// GradNorm holds the global L2 norm of the last Backward gradients before clipping
+		GradNorm float64
+		// AccumSteps sums the gradients of that many Backward calls before updating the weights,
+		// so AccumSteps batches of B rows update the weights like one batch of AccumSteps*B rows
+		AccumSteps int
+		// Step counts the training steps, Epoch is left to the training loop to advance
+		Step  int
+		Epoch int
+		// Precision is the storage precision of exported weights, "float64" or "float32"
+		Precision string
+		// Format of the files written by Export, "json" or "binary"
+		Format string
+		// Compress gzips the binary model files
+		Compress bool
+		// Strict makes Forward check every layer's output for NaN and infinite values
+		Strict bool
+		// SplitSoftmax splits the softmax layers in groups of that many nodes
+		SplitSoftmax int
+		// SoftmaxGroups holds the group sizes of every softmax layer, nil for a single group
+		SoftmaxGroups [][]int
+		// Heads are the named outputs of a network whose output activation is "heads"
+		Heads         []Head
+		Normalization *Normalization
+		// Preprocessing transforms the input rows of Forward before the Normalization
+		Preprocessing Pipeline
+		// Labels name the classes of the output nodes
+		Labels     []string
+		Metadata   map[string]string
+		accumCount int
+		source     *rngSource
+		rng        *rand.Rand
 	}
 	Layer struct {
 		Nodes            *mat64.Dense
@@ -37,11 +70,15 @@ type (
 		NodesCount       int
 		BiasWeights      *mat64.Vector
 		BiasWeightsPrev  *mat64.Vector
+		DeltaBias        *mat64.Vector
+		AccumWeights     *mat64.Dense
+		AccumBias        *mat64.Vector
 	}
 	activationFunction interface {
 		activate(*mat64.Dense, *mat64.Dense, bool, bool) error
 		backpropError(*Network, int) error
 		layerError(*mat64.Dense, [][]float64) (float64, error)
+		loss() string
 	}
 	NetData struct {
 		Nodes        []int
@@ -50,15 +87,28 @@ type (
 		BatchSize    int
 		Train        bool
 		SplitSoftmax int
+		// SoftmaxGroups gives the group sizes of every layer's softmax, uneven
+		// groups are allowed and it takes precedence over SplitSoftmax
+		SoftmaxGroups [][]int `json:",omitempty"`
+		// Heads split the output layer when its activation is "heads"
+		Heads         []Head `json:",omitempty"`
+		Precision     string
+		Normalization *Normalization    `json:",omitempty"`
+		Preprocessing Pipeline          `json:",omitempty"`
+		Labels        []string          `json:",omitempty"`
+		Metadata      map[string]string `json:",omitempty"`
+		// Seed of the network's random generator, 0 seeds it from the time
+		Seed int64 `json:",omitempty"`
 	}
 	DataWeights struct {
-		Weights     []float64
-		BiasWeights []float64
+		Weights       []float64 `json:",omitempty"`
+		BiasWeights   []float64 `json:",omitempty"`
+		Weights32     []float32 `json:",omitempty"`
+		BiasWeights32 []float32 `json:",omitempty"`
 	}
 )
 
 var activationMap = map[string]activationFunction{}
-var splitSoftmax int
 
 const (
 	ERROR_ACTIVATION_COUNT    = "[ERROR] The number of layers do not match the activation functions"
@@ -74,6 +124,25 @@ const (
 	ERROR_NOT_TRAINABLE       = "[ERROR] Network does not support training"
 	ERROR_LAYERS_IMPORT       = "[ERROR] Network Layers mismatch"
 	ERROR_WEIGHT_MISMATCH     = "[ERROR] Provided weights and bias values do not match the nework structure"
+	ERROR_UNKNOWN_PRECISION   = "[ERROR] Unknown precision, use float64 or float32"
+	ERROR_NORMALIZATION       = "[ERROR] Normalization values do not match the network's input count"
+	ERROR_FORMAT_VERSION      = "[ERROR] Unsupported model format version"
+	ERROR_MODEL_LOSS          = "[ERROR] Model loss does not match the output activation"
+	ERROR_UNKNOWN_FORMAT      = "[ERROR] Unknown model format, use json or binary"
+	ERROR_BINARY_FORMAT       = "[ERROR] Invalid binary model"
+	ERROR_PROTOBUF            = "[ERROR] Invalid protocol buffers message"
+	ERROR_ONNX_OPERATOR       = "[ERROR] Unsupported ONNX operator"
+	ERROR_ONNX_GRAPH          = "[ERROR] ONNX graph is not a chain of dense layers with supported activations"
+	ERROR_NPY_FORMAT          = "[ERROR] Unsupported NumPy array"
+	ERROR_SOFTMAX_GROUPS      = "[ERROR] Softmax groups do not add up to the nodes of a softmax layer"
+	ERROR_HEADS               = "[ERROR] Heads do not match the output layer"
+	ERROR_CHECKPOINT          = "[ERROR] Checkpoint state does not match the network structure"
+	ERROR_NOT_FINITE          = "[ERROR] Values have to be finite, not NaN or infinite"
+	ERROR_DATASET             = "[ERROR] Invalid dataset record"
+	ERROR_REWIND              = "[ERROR] The stream source can not be rewound"
+	ERROR_IDX_FORMAT          = "[ERROR] Invalid IDX file"
+	ERROR_PREPROCESSING       = "[ERROR] Preprocessing steps do not match the network's inputs"
+	ERROR_LABELS              = "[ERROR] Labels do not match the output layer"
 )
 
 func init() {
@@ -84,39 +153,80 @@ func init() {
 func New(data NetData) (*Network, error) {
 	n := new(Network)
 	if len(data.Nodes) < 2 {
-		return nil, errors.New(ERROR_LAYERS_COUNT)
+		return nil, ErrLayersCount
 	}
 	if len(data.Nodes)-1 != len(data.Activations) {
-		return nil, errors.New(ERROR_ACTIVATION_COUNT)
+		return nil, ErrActivationCount
 	}
 	if data.BatchSize <= 0 {
-		return nil, errors.New(ERROR_BATCHSIZE)
+		return nil, ErrBatchSize
 	}
 	for _, node := range data.Nodes {
 		if node < 1 {
-			return nil, errors.New(ERROR_INT_POSITIVE)
+			return nil, ErrIntPositive
 		}
 	}
 	// The other layers nodes go here
 	layerNodes := data.Nodes[1:len(data.Nodes)]
 	if data.WeightsData != nil {
 		if len(data.WeightsData) != len(layerNodes) {
-			return nil, errors.New(ERROR_WEIGHT_MISMATCH)
+			return nil, &ShapeError{Err: ErrWeightMismatch, Layer: -1, Want: []int{len(layerNodes)}, Got: []int{len(data.WeightsData)}}
 		}
+		data.WeightsData = widenWeights(data.WeightsData)
+	}
+	switch data.Precision {
+	case "", "float64", "float32":
+		n.Precision = data.Precision
+	default:
+		return nil, ErrUnknownPrecision
 	}
 	// Batchsize of the network
 	n.BatchSize = data.BatchSize
 	n.Layers = make([]Layer, len(layerNodes))
 	n.OutputLayer = len(data.Nodes) - 2
-	splitSoftmax = data.SplitSoftmax
+	n.SplitSoftmax = data.SplitSoftmax
+	if data.SoftmaxGroups != nil && len(data.SoftmaxGroups) != len(layerNodes) {
+		return nil, ErrSoftmaxGroups
+	}
+	n.SoftmaxGroups = make([][]int, len(layerNodes))
 	n.Activations = data.Activations
+	n.Metadata = cloneMetadata(data.Metadata)
 	// Create the input matrice
 	n.Input = mat64.NewDense(n.BatchSize, data.Nodes[0], nil)
 	n.InputCount = data.Nodes[0]
+	if data.Normalization != nil {
+		if len(data.Normalization.Mean) != n.InputCount || len(data.Normalization.Std) != n.InputCount {
+			return nil, ErrNormalization
+		}
+		n.Normalization = data.Normalization
+	}
+	if len(data.Preprocessing) > 0 {
+		if err := data.Preprocessing.validate(); err != nil {
+			return nil, err
+		}
+		if data.Preprocessing.Outputs() != n.InputCount {
+			return nil, &ShapeError{Err: ErrPreprocessing, Layer: -1, Want: []int{n.InputCount}, Got: []int{data.Preprocessing.Outputs()}}
+		}
+		n.Preprocessing = data.Preprocessing
+	}
+	if data.Labels != nil {
+		if _, err := NewLabelEncoder(data.Labels...); err != nil {
+			return nil, err
+		}
+		if outputs := data.Nodes[len(data.Nodes)-1]; len(data.Labels) != outputs {
+			return nil, &ShapeError{Err: ErrLabels, Layer: n.OutputLayer, Want: []int{outputs}, Got: []int{len(data.Labels)}}
+		}
+		n.Labels = data.Labels
+	}
 
 	// Initialize the Seed for Rand
-	rand.Seed(time.Now().UTC().UnixNano())
-	//rand.Seed(0)
+	seed := data.Seed
+	if seed == 0 {
+		seed = time.Now().UTC().UnixNano()
+	}
+	n.source = &rngSource{}
+	n.source.Seed(seed)
+	n.rng = rand.New(n.source)
 	// Go through all the nodes and layers
 	for k := range layerNodes {
 		n.Layers[k].NodesCount = layerNodes[k]
@@ -126,16 +236,41 @@ func New(data NetData) (*Network, error) {
 		// Check if the activation function exists
 		act, ok := activationMap[data.Activations[k]]
 		if !ok {
-			return nil, errors.New(ERROR_UNKNOWN_ACTIVATION)
+			return nil, ErrUnknownActivation
+		}
+		// Softmax layers get their own groups
+		if _, ok := act.(*softmaxFunc); ok {
+			groups, err := softmaxGroups(data, k, layerNodes[k])
+			if err != nil {
+				return nil, err
+			}
+			n.SoftmaxGroups[k] = groups
+			act = &softmaxFunc{groups: groups}
+		} else if len(data.SoftmaxGroups) > k && len(data.SoftmaxGroups[k]) > 0 {
+			return nil, ErrSoftmaxGroups
+		}
+		// The heads split the output layer
+		if _, ok := act.(*headsFunc); ok {
+			if k != len(n.Layers)-1 {
+				return nil, ErrHeads
+			}
+			heads, err := newHeads(data.Heads, layerNodes[k])
+			if err != nil {
+				return nil, err
+			}
+			n.Heads = data.Heads
+			act = heads
+		} else if k == len(n.Layers)-1 && len(data.Heads) > 0 {
+			return nil, ErrHeads
 		}
 		// Attach the activation function to the layer
 		n.Layers[k].Activation = act
 		// Create the BiasWeights vector and seed it with random values
 		if data.WeightsData == nil || data.WeightsData[k].BiasWeights == nil {
-			n.Layers[k].BiasWeights = mat64.NewVector(layerNodes[k], randomFunc(1, layerNodes[k], -1, 1))
+			n.Layers[k].BiasWeights = mat64.NewVector(layerNodes[k], randomFunc(n.rng, 1, layerNodes[k], -1, 1))
 		} else {
 			if len(data.WeightsData[k].BiasWeights) != layerNodes[k] {
-				return nil, errors.New(ERROR_WEIGHT_MISMATCH)
+				return nil, &ShapeError{Err: ErrWeightMismatch, Layer: k, Want: []int{layerNodes[k]}, Got: []int{len(data.WeightsData[k].BiasWeights)}}
 			}
 			n.Layers[k].BiasWeights = mat64.NewVector(layerNodes[k], data.WeightsData[k].BiasWeights)
 		}
@@ -144,20 +279,20 @@ func New(data NetData) (*Network, error) {
 		case 0:
 			// Create the Weights matrices and seed them with random values
 			if data.WeightsData == nil || data.WeightsData[k].Weights == nil {
-				n.Layers[k].Weights = mat64.NewDense(n.InputCount, n.Layers[k].NodesCount, randomFunc(n.InputCount, n.Layers[k].NodesCount, -1, 1))
+				n.Layers[k].Weights = mat64.NewDense(n.InputCount, n.Layers[k].NodesCount, randomFunc(n.rng, n.InputCount, n.Layers[k].NodesCount, -1, 1))
 			} else {
 				if len(data.WeightsData[k].Weights) != n.InputCount*n.Layers[k].NodesCount {
-					return nil, errors.New(ERROR_WEIGHT_MISMATCH)
+					return nil, &ShapeError{Err: ErrWeightMismatch, Layer: k, Want: []int{n.InputCount * n.Layers[k].NodesCount}, Got: []int{len(data.WeightsData[k].Weights)}}
 				}
 				n.Layers[k].Weights = mat64.NewDense(n.InputCount, n.Layers[k].NodesCount, data.WeightsData[k].Weights)
 			}
 		default:
 			// Create the Weights matrices and seed them with random values
 			if data.WeightsData == nil || data.WeightsData[k].Weights == nil {
-				n.Layers[k].Weights = mat64.NewDense(n.Layers[k-1].NodesCount, n.Layers[k].NodesCount, randomFunc(n.Layers[k-1].NodesCount, n.Layers[k].NodesCount, -1, 1))
+				n.Layers[k].Weights = mat64.NewDense(n.Layers[k-1].NodesCount, n.Layers[k].NodesCount, randomFunc(n.rng, n.Layers[k-1].NodesCount, n.Layers[k].NodesCount, -1, 1))
 			} else {
 				if len(data.WeightsData[k].Weights) != n.Layers[k-1].NodesCount*n.Layers[k].NodesCount {
-					return nil, errors.New(ERROR_WEIGHT_MISMATCH)
+					return nil, &ShapeError{Err: ErrWeightMismatch, Layer: k, Want: []int{n.Layers[k-1].NodesCount * n.Layers[k].NodesCount}, Got: []int{len(data.WeightsData[k].Weights)}}
 				}
 				n.Layers[k].Weights = mat64.NewDense(n.Layers[k-1].NodesCount, n.Layers[k].NodesCount, data.WeightsData[k].Weights)
 			}
@@ -179,18 +314,28 @@ func New(data NetData) (*Network, error) {
 		}
 		n.Layers[k].Errors = mat64.NewDense(n.Layers[k].NodesCount, n.BatchSize, nil)
 		n.Layers[k].Derivative = mat64.NewDense(n.Layers[k].NodesCount, n.BatchSize, nil)
+		n.Layers[k].DeltaBias = mat64.NewVector(n.Layers[k].NodesCount, nil)
 	}
 	return n, nil
 }
 
 // Forward takes inputs and passes through the network
 func (n *Network) Forward(in [][]float64) error {
-	if len(in) > n.BatchSize {
-		return errors.New(ERROR_WRONG_BATCH_COUNT)
+	if len(n.Preprocessing) > 0 {
+		var err error
+		if in, err = n.Preprocessing.transformBatch(in, n.BatchSize); err != nil {
+			return err
+		}
+	}
+	if err := checkRows(in, n.BatchSize, n.InputCount, -1, ErrWrongInputsCount); err != nil {
+		return err
 	}
 	var prev int
 	for k := range in {
 		n.Input.SetRow(k, in[k])
+		if n.Normalization != nil {
+			n.Normalization.apply(n.Input.RawRowView(k))
+		}
 	}
 	for i := 0; i <= n.OutputLayer; i++ {
 		prev = i - 1
@@ -200,33 +345,77 @@ func (n *Network) Forward(in [][]float64) error {
 		default:
 			n.Layers[i].Nodes.Mul(n.Layers[prev].Nodes, n.Layers[i].Weights)
 		}
+		bias := n.Layers[i].BiasWeights.RawVector()
 		for a := 0; a < n.BatchSize; a++ {
-			n.Layers[i].Nodes.RowView(a).AddVec(n.Layers[i].Nodes.RowView(a), n.Layers[i].BiasWeights)
+			row := n.Layers[i].Nodes.RawRowView(a)
+			for k := range row {
+				row[k] += bias.Data[k*bias.Inc]
+			}
+		}
+		if err := n.Layers[i].Activation.activate(n.Layers[i].Nodes, n.Layers[i].Nodes, false, false); err != nil {
+			return atLayer(err, i)
+		}
+		if n.Strict {
+			if err := checkLayer(n.Layers[i].Nodes, len(in), i); err != nil {
+				return err
+			}
 		}
-		n.Layers[i].Activation.activate(n.Layers[i].Nodes, n.Layers[i].Nodes, false, false)
 	}
 	return nil
 }
 
-// Backward takes target values and back propagates through the network
+// Backward takes target values and back propagates through the network.
+// With AccumSteps > 1 the weights are only updated every AccumSteps calls,
+// using the gradients summed over the calls
 func (n *Network) Backward(target [][]float64) error {
 	if n.isTrain == false {
-		return errors.New(ERROR_NOT_TRAINABLE)
+		return ErrNotTrainable
 	}
 	if len(target) > n.BatchSize {
-		return errors.New(ERROR_WRONG_BATCH_COUNT)
+		return fmt.Errorf("%w: got %d rows, batch size %d", ErrWrongBatchCount, len(target), n.BatchSize)
 	}
 	if n.LearnRate <= 0.0 {
-		return errors.New(ERROR_LEARN_RATE)
+		return ErrLearnRate
 	}
-	for k := range target {
-		n.Layers[n.OutputLayer].Errors.SetCol(k, target[k])
+	if err := n.gradients(target); err != nil {
+		return err
+	}
+	n.update()
+	return nil
+}
+
+// update accumulates, clips and applies the stored gradients of a batch
+func (n *Network) update() {
+	n.Step++
+	if n.AccumSteps > 1 && !n.accumulate() {
+		return
+	}
+	n.GradNorm = n.clipGradients()
+	n.applyGradients()
+}
+
+// gradients back propagates the target error and stores the weight and bias gradients of every layer
+func (n *Network) gradients(target [][]float64) error {
+	if err := checkRows(target, n.BatchSize, n.Layers[n.OutputLayer].NodesCount, n.OutputLayer, ErrDimensionsMismatch); err != nil {
+		return err
+	}
+	// The output errors are stored transposed, the rows past the target are left out
+	out := n.Layers[n.OutputLayer]
+	raw := out.Errors.RawMatrix()
+	for k := 0; k < n.BatchSize; k++ {
+		nodes := out.Nodes.RawRowView(k)
+		for r := range nodes {
+			if k < len(target) {
+				raw.Data[r*raw.Stride+k] = target[k][r] - nodes[r]
+			} else {
+				raw.Data[r*raw.Stride+k] = 0
+			}
+		}
 	}
-	n.Layers[n.OutputLayer].Errors.Sub(n.Layers[n.OutputLayer].Errors, n.Layers[n.OutputLayer].Nodes.T())
 	for i := n.OutputLayer; i >= 0; i-- {
 		err := n.Layers[i].Activation.backpropError(n, i)
 		if err != nil {
-			return err
+			return atLayer(err, i)
 		}
 		switch i {
 		case 0:
@@ -234,49 +423,124 @@ func (n *Network) Backward(target [][]float64) error {
 		default:
 			n.Layers[i].DeltaWeights.Mul(n.Layers[i].Errors, n.Layers[i-1].Nodes)
 		}
+		// Sum the errors of the batch for the bias weights
+		for r := 0; r < n.Layers[i].NodesCount; r++ {
+			var sum float64
+			for _, v := range n.Layers[i].Errors.RawRowView(r) {
+				sum += v
+			}
+			n.Layers[i].DeltaBias.SetVec(r, sum)
+		}
+	}
+	return nil
+}
 
-		// Scale the weigths update by the learning rate
-		n.Layers[i].DeltaWeights.Scale(n.LearnRate, n.Layers[i].DeltaWeights)
+// applyGradients updates the weights and bias weights with the stored gradients
+func (n *Network) applyGradients() {
+	for i := n.OutputLayer; i >= 0; i-- {
 		// Update the bias weights
-		for ib := 0; ib < n.BatchSize; ib++ {
-			n.Layers[i].BiasWeights.AddVec(n.Layers[i].Errors.ColView(ib), n.Layers[i].BiasWeights)
-		}
-		if n.Momentum > 0 {
-			n.Layers[i].DeltaWeightsPrev.Clone(n.Layers[i].DeltaWeights)
-			n.Layers[i].DeltaWeightsPrev.Scale(n.Momentum, n.Layers[i].DeltaWeightsPrev)
+		n.Layers[i].BiasWeights.AddVec(n.Layers[i].BiasWeights, n.Layers[i].DeltaBias)
+		for r := 0; r < n.Layers[i].NodesCount; r++ {
+			delta := n.Layers[i].DeltaWeights.RawRowView(r)
+			prev := n.Layers[i].DeltaWeightsPrev.RawRowView(r)
+			for c := range delta {
+				// Scale the weigths update by the learning rate
+				delta[c] *= n.LearnRate
+				if n.Momentum > 0 {
+					prev[c] = n.Momentum * delta[c]
+				}
+				delta[c] += prev[c]
+			}
 		}
-		n.Layers[i].DeltaWeights.Add(n.Layers[i].DeltaWeights, n.Layers[i].DeltaWeightsPrev)
 	}
-	// Update all the weights
+	// Update all the weights, the deltas are stored transposed
 	for i := n.OutputLayer; i >= 0; i-- {
-		n.Layers[i].Weights.Add(n.Layers[i].Weights, n.Layers[i].DeltaWeights.T())
+		raw := n.Layers[i].Weights.RawMatrix()
+		for r := 0; r < n.Layers[i].NodesCount; r++ {
+			for c, v := range n.Layers[i].DeltaWeights.RawRowView(r) {
+				raw.Data[c*raw.Stride+r] += v
+			}
+		}
 	}
-	return nil
 }
 
 // GetError return the error in the network in relation to the Cost function
 func (n *Network) NetError(target [][]float64) (float64, error) {
-	return n.Layers[n.OutputLayer].Activation.layerError(n.Layers[n.OutputLayer].Nodes, target)
+	if err := checkRows(target, n.BatchSize, n.Layers[n.OutputLayer].NodesCount, n.OutputLayer, ErrDimensionsMismatch); err != nil {
+		return 0, err
+	}
+	netError, err := n.Layers[n.OutputLayer].Activation.layerError(n.Layers[n.OutputLayer].Nodes, target)
+	return netError, atLayer(err, n.OutputLayer)
 }
 
-// GetOutput returns the values from the last layer of the network
-func (n *Network) GetOutput() [][]float64 {
+// GetOutput returns the values from the last layer of the network. The heads of
+// a multi-head network are side by side in every row, with head names only the
+// values of those heads are returned in the order of the names. An unknown head
+// name returns nil
+func (n *Network) GetOutput(heads ...string) [][]float64 {
 	var output [][]float64
 	output = make([][]float64, n.BatchSize)
 	for i := 0; i < n.BatchSize; i++ {
 		output[i] = mat64.Row(nil, i, n.Layers[n.OutputLayer].Nodes)
 	}
-	return output
+	if len(heads) == 0 {
+		return output
+	}
+	byName := splitHeads(n.Heads, output)
+	rows := make([][]float64, len(output))
+	for _, name := range heads {
+		head, ok := byName[name]
+		if !ok {
+			return nil
+		}
+		for i := range rows {
+			rows[i] = append(rows[i], head[i]...)
+		}
+	}
+	return rows
 }
 
-// Export saves the layers weights in a specified file location
+// Export saves the network as a versioned model file in a specified file location,
+// as JSON or binary depending on the network's Format.
+// With an empty path only the network data is returned
 func (n *Network) Export(path string) (NetData, error) {
+	export := n.netData()
+	if n.Precision == "float32" {
+		export.WeightsData = narrowWeights(export.WeightsData)
+	}
+	if path == "" {
+		return export, nil
+	}
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		return n.encode(w, export)
+	})
+	if err != nil {
+		return NetData{}, err
+	}
+	return export, nil
+}
+
+// netData returns the network structure and its weights at full precision
+func (n *Network) netData() NetData {
 	// Number of layers in the network
 	layersCount := len(n.Layers)
 	export := NetData{
-		Nodes:       make([]int, layersCount+1),
-		WeightsData: make([]DataWeights, layersCount),
-		Activations: make([]string, layersCount),
+		Nodes:         make([]int, layersCount+1),
+		WeightsData:   make([]DataWeights, layersCount),
+		Activations:   make([]string, layersCount),
+		BatchSize:     n.BatchSize,
+		Train:         n.isTrain,
+		SplitSoftmax:  n.SplitSoftmax,
+		Heads:         n.Heads,
+		Normalization: n.Normalization.clone(),
+		Preprocessing: n.Preprocessing.clone(),
+		Labels:        append([]string(nil), n.Labels...),
+		Metadata:      cloneMetadata(n.Metadata),
+	}
+	for _, groups := range n.SoftmaxGroups {
+		if groups != nil {
+			export.SoftmaxGroups = n.SoftmaxGroups
+		}
 	}
 	export.Nodes[0] = n.InputCount
 	for k := range n.Layers {
@@ -299,57 +563,75 @@ func (n *Network) Export(path string) (NetData, error) {
 		// Retrieve the activation functions
 		export.Activations = n.Activations
 	}
-	if path == "" {
-		return export, nil
-	}
-	// create the export file
-	dataFile, err := os.Create(path)
-	if err != nil {
-		return NetData{}, err
-	}
-	defer dataFile.Close()
+	export.Precision = n.Precision
+	return export
+}
 
-	js, err := json.Marshal(export)
-	if err != nil {
-		return NetData{}, err
+// cloneMetadata copies the metadata so the network and its exports do not share the map
+func cloneMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
 	}
-	dataFile.Write(js)
-	return export, nil
+	c := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		c[k] = v
+	}
+	return c
 }
 
-// Import takes loads layer weights in to the network
+// Import loads a network from a JSON or binary model file, older model files are migrated.
+// A batchSize of 0 uses the batch size stored in the model
 func Import(path string, batchSize int, train bool) (*Network, error) {
-	file, err := ioutil.ReadFile(path)
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
-	data := NetData{}
-	err = json.Unmarshal(file, &data)
+	defer file.Close()
+	model, err := readModel(file)
 	if err != nil {
 		return nil, err
 	}
-	data.BatchSize = batchSize
+	data := model.NetData
+	if batchSize != 0 {
+		data.BatchSize = batchSize
+	}
 	data.Train = train
-	fmt.Println("\n", data, "\n")
-	return New(data)
+	n, err := New(data)
+	if err != nil {
+		return nil, err
+	}
+	model.restore(n)
+	return n, nil
 }
 
-// Custom override the network weights
+// ImportWeights overrides the network weights. All the layers are checked
+// before any weights change, the values are copied and the momentum and
+// gradient accumulation buffers are reset since they belong to the old weights
 func (n *Network) ImportWeights(customWeights []DataWeights) error {
+	// Weights exported with the float32 precision are stored in Weights32
+	customWeights = widenWeights(customWeights)
 	if len(customWeights) != len(n.Layers) {
-		return errors.New(ERROR_WEIGHT_MISMATCH)
+		return &ShapeError{Err: ErrWeightMismatch, Layer: -1, Want: []int{len(n.Layers)}, Got: []int{len(customWeights)}}
 	}
 	for k := range n.Layers {
 		rw, cw := n.Layers[k].Weights.Dims()
 		if len(customWeights[k].Weights) != rw*cw {
-			return errors.New(ERROR_WEIGHT_MISMATCH)
+			return &ShapeError{Err: ErrWeightMismatch, Layer: k, Want: []int{rw * cw}, Got: []int{len(customWeights[k].Weights)}}
+		}
+		if len(customWeights[k].BiasWeights) != n.Layers[k].BiasWeights.Len() {
+			return &ShapeError{Err: ErrWeightMismatch, Layer: k, Want: []int{n.Layers[k].BiasWeights.Len()}, Got: []int{len(customWeights[k].BiasWeights)}}
 		}
-		_, cb := n.Layers[k].BiasWeights.Dims()
-		if len(customWeights[k].Weights) != cb {
-			return errors.New(ERROR_WEIGHT_MISMATCH)
+	}
+	for k := range n.Layers {
+		l := &n.Layers[k]
+		rw, cw := l.Weights.Dims()
+		l.Weights = mat64.NewDense(rw, cw, append([]float64(nil), customWeights[k].Weights...))
+		l.BiasWeights = mat64.NewVector(len(customWeights[k].BiasWeights), append([]float64(nil), customWeights[k].BiasWeights...))
+		if l.DeltaWeightsPrev != nil {
+			zeroDense(l.DeltaWeightsPrev)
 		}
-		n.Layers[k].Weights = mat64.NewDense(rw, cw, customWeights[k].Weights)
-		n.Layers[k].BiasWeights = mat64.NewVector(cb, customWeights[k].BiasWeights)
+		l.AccumWeights, l.AccumBias = nil, nil
 	}
+	n.accumCount = 0
 	return nil
 }
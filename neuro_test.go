@@ -1,20 +1,60 @@
 package neuro
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"testing"
 )
 
-func ImportExportTest(t *testing.T) {
+// The input and target rows of the default test network
+var (
+	testIn     = [][]float64{{1, 1, 0}, {0, 1, 1}, {1, 0, 1}}
+	testTarget = [][]float64{{1, 0, 1, 0}, {0, 1, 0, 1}, {0, 1, 0, 1}}
+)
+
+// testNetwork builds a network for the tests. Without Nodes it is the 3-10-5-4
+// network of tanh, sigmoid and softmax in groups of 2, the batch size defaults
+// to 3 and trainable networks learn at a rate of 0.1
+func testNetwork(tb testing.TB, data NetData) *Network {
+	if data.Nodes == nil {
+		data.Nodes = []int{3, 10, 5, 4}
+		data.Activations = []string{"tanh", "sigmoid", "softmax"}
+		data.SplitSoftmax = 2
+	}
+	if data.BatchSize == 0 {
+		data.BatchSize = 3
+	}
+	n, err := New(data)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if data.Train {
+		n.LearnRate = 0.1
+	}
+	return n
+}
+
+// randomRows returns input rows of the network drawn from its generator
+func randomRows(n *Network, rows int) [][]float64 {
+	in := make([][]float64, rows)
+	for k := range in {
+		in[k] = randomFunc(n.Rand(), 1, n.InputCount, -1, 1)
+	}
+	return in
+}
+
+func TestImportExport(t *testing.T) {
 	var (
 		firstOutput, secondOutput [][]float64
 	)
 
 	n, err := New(NetData{
-		Nodes:       []int{3, 10, 5, 2},
-		Activations: []string{"tanh", "sigmoid", "softmax"},
-		BatchSize:   3,
-		Train:       true,
+		Nodes:        []int{3, 10, 5, 2},
+		Activations:  []string{"tanh", "sigmoid", "softmax"},
+		BatchSize:    3,
+		Train:        true,
+		SplitSoftmax: 2,
 	})
 	if err != nil {
 		t.Error(err)
@@ -59,12 +99,13 @@ func ImportExportTest(t *testing.T) {
 	}
 }
 
-func AccuracyTest(t *testing.T) {
+func TestAccuracy(t *testing.T) {
 	n, err := New(NetData{
-		Nodes:       []int{3, 10, 5, 2},
-		Activations: []string{"tanh", "sigmoid", "softmax"},
-		BatchSize:   3,
-		Train:       true,
+		Nodes:        []int{3, 10, 5, 2},
+		Activations:  []string{"tanh", "sigmoid", "softmax"},
+		BatchSize:    3,
+		Train:        true,
+		SplitSoftmax: 2,
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -102,3 +143,67 @@ func AccuracyTest(t *testing.T) {
 		}
 	}
 }
+
+func TestImportWeights(t *testing.T) {
+	n, err := New(NetData{
+		Nodes:       []int{2, 3, 1},
+		Activations: []string{"tanh", "sigmoid"},
+		BatchSize:   1,
+		Train:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.LearnRate = 0.1
+	n.Momentum = 0.5
+	in, target := [][]float64{{1, 0}}, [][]float64{{1}}
+	if err := n.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Backward(target); err != nil {
+		t.Fatal(err)
+	}
+	weights := []DataWeights{
+		{Weights: []float64{1, 2, 3, 4, 5, 6}, BiasWeights: []float64{0.1, 0.2, 0.3}},
+		{Weights: []float64{1, -1, 0.5}, BiasWeights: []float64{0.5}},
+	}
+	// A bad last layer must not change the first one
+	bad := []DataWeights{weights[0], {Weights: []float64{1, -1}, BiasWeights: []float64{0.5}}}
+	before := n.Layers[0].Weights.At(0, 0)
+	err = n.ImportWeights(bad)
+	var shapeErr *ShapeError
+	if !errors.As(err, &shapeErr) || shapeErr.Layer != 1 || fmt.Sprint(shapeErr.Want, shapeErr.Got) != "[3] [2]" {
+		t.Errorf("Expected a shape error for the weights of layer 1, got %v", err)
+	}
+	if n.Layers[0].Weights.At(0, 0) != before {
+		t.Error("A failed import changed the weights")
+	}
+	if err := n.ImportWeights(weights); err != nil {
+		t.Fatal(err)
+	}
+	weights[1].Weights[0] = 100
+	if n.Layers[1].Weights.At(0, 0) != 1 || n.Layers[0].Weights.At(1, 2) != 6 || n.Layers[0].BiasWeights.At(2, 0) != 0.3 {
+		t.Error("The weights were not copied")
+	}
+	for k := range n.Layers {
+		r, c := n.Layers[k].DeltaWeightsPrev.Dims()
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				if n.Layers[k].DeltaWeightsPrev.At(i, j) != 0 {
+					t.Fatalf("Layer %d momentum was not reset", k)
+				}
+			}
+		}
+	}
+	// Weights exported with the float32 precision
+	weights32 := []DataWeights{
+		{Weights32: []float32{1, 2, 3, 4, 5, 6}, BiasWeights32: []float32{0.1, 0.2, 0.3}},
+		{Weights32: []float32{-1, -1, 0.5}, BiasWeights32: []float32{0.5}},
+	}
+	if err := n.ImportWeights(weights32); err != nil {
+		t.Fatal(err)
+	}
+	if n.Layers[1].Weights.At(0, 0) != -1 || n.Layers[0].BiasWeights.At(2, 0) != float64(float32(0.3)) {
+		t.Error("The float32 weights were not imported")
+	}
+}
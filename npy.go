@@ -0,0 +1,143 @@
+package neuro
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	npyMagic   = []byte("\x93NUMPY")
+	npyDescr   = regexp.MustCompile(`'descr':\s*'([<>|=]?)([fi])(\d)'`)
+	npyFortran = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape':\s*\(([\d,\s]*)\)`)
+)
+
+// readNPYFile reads a NumPy .npy file, see readNPY
+func readNPYFile(path string) ([][]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readNPY(file)
+}
+
+// readNPY reads a little-endian float or int NumPy array of at most two
+// dimensions. A vector is returned as a single row
+func readNPY(r io.Reader) ([][]float64, error) {
+	br := bufio.NewReader(r)
+	prefix := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(br, prefix); err != nil {
+		return nil, err
+	}
+	if string(prefix[:len(npyMagic)]) != string(npyMagic) {
+		return nil, ErrNPYFormat
+	}
+	// Version 1 stores the header length in 2 bytes, later versions in 4
+	var headerLen uint32
+	switch prefix[len(npyMagic)] {
+	case 1:
+		var n uint16
+		if err := binary.Read(br, binary.LittleEndian, &n); err != nil {
+			return nil, err
+		}
+		headerLen = uint32(n)
+	case 2, 3:
+		if err := binary.Read(br, binary.LittleEndian, &headerLen); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrNPYFormat
+	}
+	if headerLen > maxBinaryHeader {
+		return nil, ErrNPYFormat
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, err
+	}
+	descr := npyDescr.FindStringSubmatch(string(header))
+	fortran := npyFortran.FindStringSubmatch(string(header))
+	shape := npyShape.FindStringSubmatch(string(header))
+	if descr == nil || fortran == nil || shape == nil || descr[1] == ">" {
+		return nil, fmt.Errorf("%w: %s", ErrNPYFormat, header)
+	}
+	rows, cols := 1, 1
+	var dims []int
+	for _, d := range strings.Split(shape[1], ",") {
+		if d = strings.TrimSpace(d); d == "" {
+			continue
+		}
+		v, err := strconv.Atoi(d)
+		if err != nil {
+			return nil, err
+		}
+		dims = append(dims, v)
+	}
+	switch len(dims) {
+	case 0:
+	case 1:
+		cols = dims[0]
+	case 2:
+		rows, cols = dims[0], dims[1]
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrNPYFormat, header)
+	}
+	kind := descr[2] + descr[3]
+	if kind != "f4" && kind != "f8" && kind != "i4" && kind != "i8" {
+		return nil, fmt.Errorf("%w: %s", ErrNPYFormat, header)
+	}
+	// Rows without columns still count as a value each, the product can not overflow
+	width := cols
+	if width < 1 {
+		width = 1
+	}
+	if rows > maxReadValues/width {
+		return nil, fmt.Errorf("%w: array of %d x %d values is too large", ErrNPYFormat, rows, cols)
+	}
+	values, err := readNPYValues(br, rows*cols, kind)
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]float64, rows)
+	for r := range out {
+		out[r] = make([]float64, cols)
+		for c := range out[r] {
+			// Fortran order stores the values column by column
+			if fortran[1] == "True" {
+				out[r][c] = values[c*rows+r]
+			} else {
+				out[r][c] = values[r*cols+c]
+			}
+		}
+	}
+	return out, nil
+}
+
+// readNPYValues reads count values of the kind
+func readNPYValues(r io.Reader, count int, kind string) ([]float64, error) {
+	switch kind {
+	case "f4":
+		return readChunked(r, count, 4, func(b []byte) float64 {
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		})
+	case "f8":
+		return readChunked(r, count, 8, func(b []byte) float64 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		})
+	case "i4":
+		return readChunked(r, count, 4, func(b []byte) float64 {
+			return float64(int32(binary.LittleEndian.Uint32(b)))
+		})
+	}
+	return readChunked(r, count, 8, func(b []byte) float64 {
+		return float64(int64(binary.LittleEndian.Uint64(b)))
+	})
+}
@@ -0,0 +1,854 @@
+package neuro
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// The subset of the ONNX protobuf messages used to export and import dense networks
+type (
+	onnxModel struct {
+		IRVersion       int64
+		ProducerName    string
+		ProducerVersion string
+		Opsets          []onnxOpset
+		Graph           onnxGraph
+	}
+	onnxOpset struct {
+		Domain  string
+		Version int64
+	}
+	onnxGraph struct {
+		Name         string
+		Nodes        []onnxNode
+		Initializers []onnxTensor
+		Inputs       []onnxValueInfo
+		Outputs      []onnxValueInfo
+	}
+	onnxNode struct {
+		Name       string
+		OpType     string
+		Domain     string
+		Inputs     []string
+		Outputs    []string
+		Attributes []onnxAttribute
+	}
+	onnxAttribute struct {
+		Name string
+		Type int64
+		F    float64
+		I    int64
+		Ints []int64
+	}
+	// onnxTensor holds float and double data in Values and int64 data in Int64s
+	onnxTensor struct {
+		Name     string
+		DataType int64
+		Dims     []int64
+		Values   []float64
+		Int64s   []int64
+	}
+	// onnxValueInfo describes a graph input or output, a dimension of -1 has no fixed size
+	onnxValueInfo struct {
+		Name     string
+		ElemType int64
+		Dims     []int64
+	}
+	// onnxBuilder adds the nodes and initializers of a network to a graph
+	onnxBuilder struct {
+		graph    onnxGraph
+		elemType int64
+		prefix   string
+		consts   map[float64]string
+	}
+)
+
+// ONNX tensor data types and attribute types
+const (
+	onnxFloat  = 1
+	onnxInt64  = 7
+	onnxDouble = 11
+
+	onnxAttrFloat = 1
+	onnxAttrInt   = 2
+	onnxAttrInts  = 7
+)
+
+const (
+	onnxIRVersion    = 7
+	onnxOpsetVersion = 13
+)
+
+// onnxActivationMap adds the nodes of an activation function on the input
+// tensor of a layer and returns the name of the activated tensor
+var onnxActivationMap = map[string]func(b *onnxBuilder, n *Network, layer int, in string) string{}
+
+// ExportONNX saves the network as an ONNX model in a specified file location
+func (n *Network) ExportONNX(path string) error {
+	return writeFileAtomic(path, n.EncodeONNX)
+}
+
+// EncodeONNX writes the network to w as an ONNX model. Every layer is a Gemm
+// node followed by the nodes of its activation function. The graph reads the
+// "input" tensor of shape [batch, InputCount] and writes the "output" tensor.
+// The values are float32 when the network's Precision is float32, else float64
+func (n *Network) EncodeONNX(w io.Writer) error {
+	model, err := n.onnxModel()
+	if err != nil {
+		return err
+	}
+	p := &protoWriter{}
+	model.marshal(p)
+	_, err = w.Write(p.buf)
+	return err
+}
+
+func (n *Network) onnxModel() (onnxModel, error) {
+	b := &onnxBuilder{elemType: onnxDouble, consts: map[float64]string{}}
+	if n.Precision == "float32" {
+		b.elemType = onnxFloat
+	}
+	b.graph.Name = "neuro"
+	b.graph.Inputs = []onnxValueInfo{{Name: "input", ElemType: b.elemType, Dims: []int64{-1, int64(n.InputCount)}}}
+	if len(n.Preprocessing) > 0 {
+		return onnxModel{}, fmt.Errorf("%w: the preprocessing steps can not be exported to ONNX", ErrPreprocessing)
+	}
+	x := "input"
+	if n.Normalization != nil {
+		b.prefix = "normalization"
+		mean := b.tensor("normalization.mean", []int64{int64(n.InputCount)}, n.Normalization.Mean)
+		// Forward only centers the inputs with a zero Std, dividing by 1 does the same
+		scale := make([]float64, n.InputCount)
+		for k, v := range n.Normalization.Std {
+			scale[k] = v
+			if v == 0 {
+				scale[k] = 1
+			}
+		}
+		std := b.tensor("normalization.std", []int64{int64(n.InputCount)}, scale)
+		x = b.node("Div", b.node("Sub", x, mean), std)
+	}
+	for k := range n.Layers {
+		l := &n.Layers[k]
+		activation, ok := onnxActivationMap[n.Activations[k]]
+		if !ok {
+			return onnxModel{}, ErrUnknownActivation
+		}
+		b.prefix = fmt.Sprintf("layer%d", k)
+		rows, cols := l.Weights.Dims()
+		weights := b.tensor(b.prefix+".weight", []int64{int64(rows), int64(cols)}, denseData(l.Weights))
+		bias := b.tensor(b.prefix+".bias", []int64{int64(cols)}, mat64.Col(nil, 0, l.BiasWeights))
+		x = activation(b, n, k, b.node("Gemm", x, weights, bias))
+	}
+	// The last node writes the network's output
+	b.graph.Nodes[len(b.graph.Nodes)-1].Outputs[0] = "output"
+	b.graph.Outputs = []onnxValueInfo{{Name: "output", ElemType: b.elemType, Dims: []int64{-1, int64(n.Layers[n.OutputLayer].NodesCount)}}}
+	return onnxModel{
+		IRVersion:       onnxIRVersion,
+		ProducerName:    "neuro",
+		ProducerVersion: Version,
+		Opsets:          []onnxOpset{{Version: onnxOpsetVersion}},
+		Graph:           b.graph,
+	}, nil
+}
+
+// node adds a node with the given inputs and returns the name of its output
+func (b *onnxBuilder) node(op string, inputs ...string) string {
+	return b.nodeAttr(op, nil, inputs...)
+}
+
+func (b *onnxBuilder) nodeAttr(op string, attrs []onnxAttribute, inputs ...string) string {
+	return b.nodeOutputs(op, attrs, 1, inputs...)[0]
+}
+
+// nodeOutputs adds a node with several outputs and returns their names
+func (b *onnxBuilder) nodeOutputs(op string, attrs []onnxAttribute, outputs int, inputs ...string) []string {
+	name := fmt.Sprintf("%s/%s_%d", b.prefix, op, len(b.graph.Nodes))
+	node := onnxNode{
+		Name:       name,
+		OpType:     op,
+		Inputs:     inputs,
+		Outputs:    []string{name},
+		Attributes: attrs,
+	}
+	for k := 1; k < outputs; k++ {
+		node.Outputs = append(node.Outputs, fmt.Sprintf("%s:%d", name, k))
+	}
+	b.graph.Nodes = append(b.graph.Nodes, node)
+	return node.Outputs
+}
+
+// tensor adds an initializer with the builder's data type
+func (b *onnxBuilder) tensor(name string, dims []int64, values []float64) string {
+	b.graph.Initializers = append(b.graph.Initializers, onnxTensor{Name: name, DataType: b.elemType, Dims: dims, Values: values})
+	return name
+}
+
+// shape adds an int64 initializer used as the shape input of Reshape
+func (b *onnxBuilder) shape(name string, dims ...int64) string {
+	b.graph.Initializers = append(b.graph.Initializers, onnxTensor{Name: name, DataType: onnxInt64, Dims: []int64{int64(len(dims))}, Int64s: dims})
+	return name
+}
+
+// constant returns a scalar initializer shared by the whole graph
+func (b *onnxBuilder) constant(v float64) string {
+	if name, ok := b.consts[v]; ok {
+		return name
+	}
+	name := fmt.Sprintf("const.%v", v)
+	b.graph.Initializers = append(b.graph.Initializers, onnxTensor{Name: name, DataType: b.elemType, Values: []float64{v}})
+	b.consts[v] = name
+	return name
+}
+
+func (m *onnxModel) marshal(w *protoWriter) {
+	w.varint(1, uint64(m.IRVersion))
+	w.string(2, m.ProducerName)
+	w.string(3, m.ProducerVersion)
+	w.message(7, m.Graph.marshal)
+	for k := range m.Opsets {
+		opset := m.Opsets[k]
+		w.message(8, func(w *protoWriter) {
+			if opset.Domain != "" {
+				w.string(1, opset.Domain)
+			}
+			w.varint(2, uint64(opset.Version))
+		})
+	}
+}
+
+func (g *onnxGraph) marshal(w *protoWriter) {
+	for k := range g.Nodes {
+		w.message(1, g.Nodes[k].marshal)
+	}
+	w.string(2, g.Name)
+	for k := range g.Initializers {
+		w.message(5, g.Initializers[k].marshal)
+	}
+	for k := range g.Inputs {
+		w.message(11, g.Inputs[k].marshal)
+	}
+	for k := range g.Outputs {
+		w.message(12, g.Outputs[k].marshal)
+	}
+}
+
+func (node *onnxNode) marshal(w *protoWriter) {
+	for _, in := range node.Inputs {
+		w.string(1, in)
+	}
+	for _, out := range node.Outputs {
+		w.string(2, out)
+	}
+	w.string(3, node.Name)
+	w.string(4, node.OpType)
+	for k := range node.Attributes {
+		w.message(5, node.Attributes[k].marshal)
+	}
+	if node.Domain != "" {
+		w.string(7, node.Domain)
+	}
+}
+
+func (a *onnxAttribute) marshal(w *protoWriter) {
+	w.string(1, a.Name)
+	switch a.Type {
+	case onnxAttrFloat:
+		w.fixed32(2, math.Float32bits(float32(a.F)))
+	case onnxAttrInt:
+		w.varint(3, uint64(a.I))
+	case onnxAttrInts:
+		w.packedVarints(8, a.Ints)
+	}
+	w.varint(20, uint64(a.Type))
+}
+
+func (t *onnxTensor) marshal(w *protoWriter) {
+	if len(t.Dims) > 0 {
+		w.packedVarints(1, t.Dims)
+	}
+	w.varint(2, uint64(t.DataType))
+	switch t.DataType {
+	case onnxFloat:
+		w.packedFloats(4, t.Values)
+	case onnxInt64:
+		w.packedVarints(7, t.Int64s)
+	case onnxDouble:
+		w.packedDoubles(10, t.Values)
+	}
+	w.string(8, t.Name)
+}
+
+func (v *onnxValueInfo) marshal(w *protoWriter) {
+	w.string(1, v.Name)
+	// TypeProto.tensor_type with its elem_type and shape
+	w.message(2, func(w *protoWriter) {
+		w.message(1, func(w *protoWriter) {
+			w.varint(1, uint64(v.ElemType))
+			w.message(2, func(w *protoWriter) {
+				for _, dim := range v.Dims {
+					w.message(1, func(w *protoWriter) {
+						if dim < 0 {
+							w.string(2, "batch")
+						} else {
+							w.varint(1, uint64(dim))
+						}
+					})
+				}
+			})
+		})
+	})
+}
+
+// parseONNX decodes an ONNX model
+func parseONNX(buf []byte) (onnxModel, error) {
+	m := onnxModel{}
+	fields, err := parseProto(buf)
+	if err != nil {
+		return m, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			m.IRVersion = int64(f.v)
+		case 2:
+			m.ProducerName = string(f.b)
+		case 3:
+			m.ProducerVersion = string(f.b)
+		case 7:
+			if m.Graph, err = parseONNXGraph(f.b); err != nil {
+				return m, err
+			}
+		case 8:
+			opset := onnxOpset{}
+			sub, err := parseProto(f.b)
+			if err != nil {
+				return m, err
+			}
+			for _, f := range sub {
+				switch f.num {
+				case 1:
+					opset.Domain = string(f.b)
+				case 2:
+					opset.Version = int64(f.v)
+				}
+			}
+			m.Opsets = append(m.Opsets, opset)
+		}
+	}
+	return m, nil
+}
+
+func parseONNXGraph(buf []byte) (onnxGraph, error) {
+	g := onnxGraph{}
+	fields, err := parseProto(buf)
+	if err != nil {
+		return g, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			node, err := parseONNXNode(f.b)
+			if err != nil {
+				return g, err
+			}
+			g.Nodes = append(g.Nodes, node)
+		case 2:
+			g.Name = string(f.b)
+		case 5:
+			t, err := parseONNXTensor(f.b)
+			if err != nil {
+				return g, err
+			}
+			g.Initializers = append(g.Initializers, t)
+		case 11, 12:
+			v, err := parseONNXValueInfo(f.b)
+			if err != nil {
+				return g, err
+			}
+			if f.num == 11 {
+				g.Inputs = append(g.Inputs, v)
+			} else {
+				g.Outputs = append(g.Outputs, v)
+			}
+		}
+	}
+	return g, nil
+}
+
+func parseONNXNode(buf []byte) (onnxNode, error) {
+	node := onnxNode{}
+	fields, err := parseProto(buf)
+	if err != nil {
+		return node, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			node.Inputs = append(node.Inputs, string(f.b))
+		case 2:
+			node.Outputs = append(node.Outputs, string(f.b))
+		case 3:
+			node.Name = string(f.b)
+		case 4:
+			node.OpType = string(f.b)
+		case 5:
+			a, err := parseONNXAttribute(f.b)
+			if err != nil {
+				return node, err
+			}
+			node.Attributes = append(node.Attributes, a)
+		case 7:
+			node.Domain = string(f.b)
+		}
+	}
+	return node, nil
+}
+
+func parseONNXAttribute(buf []byte) (onnxAttribute, error) {
+	a := onnxAttribute{}
+	fields, err := parseProto(buf)
+	if err != nil {
+		return a, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			a.Name = string(f.b)
+		case 2:
+			a.F = float64(math.Float32frombits(uint32(f.v)))
+		case 3:
+			a.I = int64(f.v)
+		case 8:
+			ints, err := f.varints()
+			if err != nil {
+				return a, err
+			}
+			a.Ints = append(a.Ints, ints...)
+		case 20:
+			a.Type = int64(f.v)
+		}
+	}
+	return a, nil
+}
+
+func parseONNXTensor(buf []byte) (onnxTensor, error) {
+	t := onnxTensor{}
+	fields, err := parseProto(buf)
+	if err != nil {
+		return t, err
+	}
+	var raw []byte
+	for _, f := range fields {
+		var values []float64
+		var ints []int64
+		switch f.num {
+		case 1:
+			ints, err = f.varints()
+			t.Dims = append(t.Dims, ints...)
+		case 2:
+			t.DataType = int64(f.v)
+		case 4:
+			values, err = f.floats()
+			t.Values = append(t.Values, values...)
+		case 7:
+			ints, err = f.varints()
+			t.Int64s = append(t.Int64s, ints...)
+		case 8:
+			t.Name = string(f.b)
+		case 9:
+			raw = f.b
+		case 10:
+			values, err = f.doubles()
+			t.Values = append(t.Values, values...)
+		}
+		if err != nil {
+			return t, err
+		}
+	}
+	if raw == nil {
+		return t, nil
+	}
+	// raw_data holds the values little-endian
+	switch t.DataType {
+	case onnxFloat:
+		t.Values, err = protoField{wire: wireBytes, b: raw}.floats()
+	case onnxDouble:
+		t.Values, err = protoField{wire: wireBytes, b: raw}.doubles()
+	case onnxInt64:
+		if len(raw)%8 != 0 {
+			return t, ErrProtobuf
+		}
+		t.Int64s = make([]int64, len(raw)/8)
+		for k := range t.Int64s {
+			t.Int64s[k] = int64(binary.LittleEndian.Uint64(raw[8*k:]))
+		}
+	}
+	return t, err
+}
+
+func parseONNXValueInfo(buf []byte) (onnxValueInfo, error) {
+	v := onnxValueInfo{}
+	fields, err := parseProto(buf)
+	if err != nil {
+		return v, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			v.Name = string(f.b)
+		case 2:
+			// TypeProto.tensor_type
+			typ, err := onnxSubMessage(f.b, 1)
+			if err != nil {
+				return v, err
+			}
+			tensor, err := parseProto(typ)
+			if err != nil {
+				return v, err
+			}
+			for _, f := range tensor {
+				switch f.num {
+				case 1:
+					v.ElemType = int64(f.v)
+				case 2:
+					dims, err := parseProto(f.b)
+					if err != nil {
+						return v, err
+					}
+					for _, dim := range dims {
+						size := int64(-1)
+						value, err := parseProto(dim.b)
+						if err != nil {
+							return v, err
+						}
+						for _, f := range value {
+							if f.num == 1 && f.wire == wireVarint {
+								size = int64(f.v)
+							}
+						}
+						v.Dims = append(v.Dims, size)
+					}
+				}
+			}
+		}
+	}
+	return v, nil
+}
+
+// onnxSubMessage returns the bytes of a nested message field
+func onnxSubMessage(buf []byte, num int) ([]byte, error) {
+	fields, err := parseProto(buf)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		if f.num == num {
+			return f.b, nil
+		}
+	}
+	return nil, nil
+}
+
+// ImportONNX loads a dense network from an ONNX model file, see DecodeONNX
+func ImportONNX(path string, batchSize int, train bool) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return DecodeONNX(file, batchSize, train)
+}
+
+// DecodeONNX reads an ONNX model from r and builds the equivalent network.
+// The graph has to be a chain of dense layers, each one a Gemm node or a
+// MatMul node with an optional Add of the bias, followed by a Sigmoid, Tanh,
+// Relu or Softmax node. Identity and Dropout nodes are skipped, Sub and Div
+// nodes on the input become the network's Normalization, and the grouped
+// softmax and tanh nodes written by ExportONNX are recognized. Tanh layers use
+// the network's tanh approximation
+func DecodeONNX(r io.Reader, batchSize int, train bool) (*Network, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	m, err := parseONNX(buf)
+	if err != nil {
+		return nil, err
+	}
+	data, err := m.netData()
+	if err != nil {
+		return nil, err
+	}
+	data.BatchSize = batchSize
+	data.Train = train
+	return New(data)
+}
+
+// onnxImportOps are the operators DecodeONNX understands
+var onnxImportOps = map[string]bool{
+	"Gemm": true, "MatMul": true, "Add": true, "Sub": true, "Div": true,
+	"Sigmoid": true, "Tanh": true, "Relu": true, "Softmax": true, "Clip": true, "Reshape": true, "Split": true,
+	"Identity": true, "Dropout": true,
+}
+
+// netData walks the chain of nodes from the graph input and collects the dense layers
+func (m *onnxModel) netData() (NetData, error) {
+	nodes := m.Graph.Nodes
+	inits := map[string]*onnxTensor{}
+	for k := range m.Graph.Initializers {
+		inits[m.Graph.Initializers[k].Name] = &m.Graph.Initializers[k]
+	}
+	// Older models also list the initializers as graph inputs
+	x := ""
+	for _, in := range m.Graph.Inputs {
+		if _, ok := inits[in.Name]; !ok {
+			x = in.Name
+			break
+		}
+	}
+	data := NetData{}
+	// dense is the layer waiting for its bias or activation, with size nodes
+	var dense *DataWeights
+	var size int
+	// groups of the softmax of that layer
+	var groups []int
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[i]
+		if (node.Domain != "" && node.Domain != "ai.onnx") || !onnxImportOps[node.OpType] {
+			return NetData{}, onnxOperatorError(node)
+		}
+		other, ok := onnxOperand(node, x)
+		if !ok || len(node.Outputs) == 0 {
+			return NetData{}, onnxGraphError(node)
+		}
+		activation := ""
+		switch node.OpType {
+		case "Gemm", "MatMul":
+			w := inits[other]
+			// Both dimensions are at most the number of values, so their product can not overflow
+			if dense != nil || w == nil || len(w.Dims) != 2 || w.Dims[0] < 1 || w.Dims[1] < 1 ||
+				w.Dims[0] > int64(len(w.Values)) || w.Dims[1] > int64(len(w.Values)) {
+				return NetData{}, onnxGraphError(node)
+			}
+			in, out := int(w.Dims[0]), int(w.Dims[1])
+			values := w.Values
+			if node.OpType == "Gemm" {
+				if onnxIntAttr(node, "transA", 0) != 0 || onnxFloatAttr(node, "alpha", 1) != 1 || onnxFloatAttr(node, "beta", 1) != 1 {
+					return NetData{}, onnxOperatorError(node)
+				}
+				if onnxIntAttr(node, "transB", 0) != 0 {
+					in, out = out, in
+					values = transposeValues(values, out, in)
+				}
+			}
+			if len(values) != in*out {
+				return NetData{}, ErrWeightMismatch
+			}
+			if len(data.Nodes) == 0 {
+				data.Nodes = []int{in}
+			} else if data.Nodes[len(data.Nodes)-1] != in {
+				return NetData{}, ErrWeightMismatch
+			}
+			dense = &DataWeights{Weights: values}
+			size = out
+			if node.OpType == "Gemm" && len(node.Inputs) > 2 {
+				b := inits[node.Inputs[2]]
+				if b == nil || len(b.Values) != size {
+					return NetData{}, onnxGraphError(node)
+				}
+				dense.BiasWeights = b.Values
+			}
+			data.Precision = "float64"
+			if w.DataType == onnxFloat {
+				data.Precision = "float32"
+			}
+		case "Add":
+			b := inits[other]
+			if dense == nil || dense.BiasWeights != nil || b == nil || len(b.Values) != size {
+				return NetData{}, onnxGraphError(node)
+			}
+			dense.BiasWeights = b.Values
+		case "Sub", "Div":
+			// Only the input normalization is supported: Sub the mean, then Div by the std
+			t := inits[other]
+			if t == nil || (node.OpType == "Sub" && i != 0) || (node.OpType == "Div" && (i != 1 || nodes[0].OpType != "Sub")) {
+				return NetData{}, onnxGraphError(node)
+			}
+			if node.OpType == "Sub" {
+				data.Normalization = &Normalization{Mean: t.Values}
+			} else {
+				data.Normalization.Std = t.Values
+			}
+		case "Sigmoid":
+			activation = "sigmoid"
+		case "Tanh":
+			activation = "tanh"
+		case "Relu":
+			activation = "relu"
+		case "Softmax":
+			if axis := onnxIntAttr(node, "axis", -1); axis != -1 && axis != 1 {
+				return NetData{}, onnxOperatorError(node)
+			}
+			activation = "softmax"
+		case "Clip":
+			if !isONNXTanh(nodes[i:], inits) {
+				return NetData{}, onnxGraphError(node)
+			}
+			activation = "tanh"
+			i += 6
+		case "Reshape":
+			// Even softmax groups: Reshape to [batch, groups, size], Softmax, Reshape back
+			// The groups must cover the layer, which also bounds their count
+			shape := inits[other]
+			if dense == nil || i+2 >= len(nodes) || shape == nil || len(shape.Int64s) != 3 ||
+				shape.Int64s[1] < 1 || shape.Int64s[2] < 1 || shape.Int64s[2] > int64(size) ||
+				shape.Int64s[1] != int64(size)/shape.Int64s[2] || shape.Int64s[1]*shape.Int64s[2] != int64(size) ||
+				nodes[i+1].OpType != "Softmax" || nodes[i+2].OpType != "Reshape" ||
+				!onnxFollows(nodes[i+1], node, 0) || !onnxFollows(nodes[i+2], nodes[i+1], 0) ||
+				len(nodes[i+2].Inputs) != 2 || inits[nodes[i+2].Inputs[1]] == nil {
+				return NetData{}, onnxGraphError(node)
+			}
+			activation = "softmax"
+			for k := int64(0); k < shape.Int64s[1]; k++ {
+				groups = append(groups, int(shape.Int64s[2]))
+			}
+			i += 2
+		case "Split":
+			// Uneven softmax groups: Split, a Softmax per group, Concat
+			split := inits[other]
+			count := len(node.Outputs)
+			if dense == nil || split == nil || len(split.Int64s) != count || onnxIntAttr(node, "axis", 0) != 1 ||
+				i+count+1 >= len(nodes) || nodes[i+count+1].OpType != "Concat" || len(nodes[i+count+1].Inputs) != count {
+				return NetData{}, onnxGraphError(node)
+			}
+			concat := nodes[i+count+1]
+			for k := 0; k < count; k++ {
+				softmax := nodes[i+1+k]
+				if softmax.OpType != "Softmax" || split.Int64s[k] < 1 || len(softmax.Inputs) == 0 || softmax.Inputs[0] != node.Outputs[k] || !onnxFollows(concat, softmax, k) {
+					return NetData{}, onnxGraphError(node)
+				}
+				groups = append(groups, int(split.Int64s[k]))
+			}
+			activation = "softmax"
+			i += count + 1
+		}
+		if len(nodes[i].Outputs) == 0 {
+			return NetData{}, onnxGraphError(nodes[i])
+		}
+		x = nodes[i].Outputs[0]
+		if activation == "" {
+			continue
+		}
+		if dense == nil {
+			return NetData{}, onnxGraphError(node)
+		}
+		if dense.BiasWeights == nil {
+			dense.BiasWeights = make([]float64, size)
+		}
+		data.Nodes = append(data.Nodes, size)
+		data.Activations = append(data.Activations, activation)
+		data.WeightsData = append(data.WeightsData, *dense)
+		data.SoftmaxGroups = append(data.SoftmaxGroups, groups)
+		dense = nil
+		groups = nil
+	}
+	if dense != nil || len(data.Activations) == 0 || (data.Normalization != nil && data.Normalization.Std == nil) || (len(m.Graph.Outputs) > 0 && m.Graph.Outputs[0].Name != x) {
+		return NetData{}, ErrONNXGraph
+	}
+	return data, nil
+}
+
+// onnxOperand returns the operand of the node that is not x and reports
+// whether the node reads x, keeping the graph a single chain
+func onnxOperand(node onnxNode, x string) (string, bool) {
+	switch {
+	case len(node.Inputs) == 0:
+		return "", false
+	case node.Inputs[0] == x:
+		if len(node.Inputs) > 1 {
+			return node.Inputs[1], true
+		}
+		return "", true
+	case node.OpType == "Add" && len(node.Inputs) == 2 && node.Inputs[1] == x:
+		return node.Inputs[0], true
+	}
+	return "", false
+}
+
+// onnxFollows reports whether input k of the node reads the first output of prev
+func onnxFollows(node, prev onnxNode, k int) bool {
+	return len(node.Inputs) > k && len(prev.Outputs) > 0 && node.Inputs[k] == prev.Outputs[0]
+}
+
+// isONNXTanh reports whether the nodes start with the tanh approximation written by tanhONNX:
+// v = Clip(x, -3, 3), sq = v*v and (v * (sq + 27)) / (sq*9 + 27)
+func isONNXTanh(nodes []onnxNode, inits map[string]*onnxTensor) bool {
+	ops := []string{"Clip", "Mul", "Add", "Mul", "Mul", "Add", "Div"}
+	if len(nodes) < len(ops) || len(nodes[0].Inputs) != 3 || len(nodes[0].Outputs) == 0 {
+		return false
+	}
+	for k, op := range ops {
+		if nodes[k].OpType != op || (k > 0 && len(nodes[k].Inputs) != 2) {
+			return false
+		}
+	}
+	constant := func(name string, v float64) bool {
+		t := inits[name]
+		return t != nil && len(t.Values) == 1 && t.Values[0] == v
+	}
+	clip, sq, sum, num, mul, den, div := nodes[0], nodes[1], nodes[2], nodes[3], nodes[4], nodes[5], nodes[6]
+	return constant(clip.Inputs[1], -3) && constant(clip.Inputs[2], 3) &&
+		onnxFollows(sq, clip, 0) && onnxFollows(sq, clip, 1) &&
+		onnxFollows(sum, sq, 0) && constant(sum.Inputs[1], 27) &&
+		onnxFollows(num, clip, 0) && onnxFollows(num, sum, 1) &&
+		onnxFollows(mul, sq, 0) && constant(mul.Inputs[1], 9) &&
+		onnxFollows(den, mul, 0) && constant(den.Inputs[1], 27) &&
+		onnxFollows(div, num, 0) && onnxFollows(div, den, 1)
+}
+
+func onnxIntAttr(node onnxNode, name string, def int64) int64 {
+	for _, a := range node.Attributes {
+		if a.Name == name {
+			return a.I
+		}
+	}
+	return def
+}
+
+func onnxFloatAttr(node onnxNode, name string, def float64) float64 {
+	for _, a := range node.Attributes {
+		if a.Name == name {
+			return a.F
+		}
+	}
+	return def
+}
+
+// transposeValues transposes a row major rows x cols matrice
+func transposeValues(values []float64, rows, cols int) []float64 {
+	if len(values) != rows*cols {
+		return values
+	}
+	out := make([]float64, len(values))
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			out[c*rows+r] = values[r*cols+c]
+		}
+	}
+	return out
+}
+
+func onnxOperatorError(node onnxNode) error {
+	return fmt.Errorf("%w: %s (%s)", ErrONNXOperator, node.OpType, node.Name)
+}
+
+func onnxGraphError(node onnxNode) error {
+	return fmt.Errorf("%w: %s (%s)", ErrONNXGraph, node.OpType, node.Name)
+}
@@ -0,0 +1,474 @@
+package neuro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// onnxValue is a tensor of the test evaluator
+type onnxValue struct {
+	dims []int64
+	data []float64
+}
+
+// evalONNX runs the graph on a batch of inputs with the operators the exporter uses
+func evalONNX(m onnxModel, in [][]float64) ([][]float64, error) {
+	values := map[string]onnxValue{}
+	for _, t := range m.Graph.Initializers {
+		data := t.Values
+		if t.DataType == onnxInt64 {
+			data = make([]float64, len(t.Int64s))
+			for k, v := range t.Int64s {
+				data[k] = float64(v)
+			}
+		}
+		values[t.Name] = onnxValue{dims: t.Dims, data: data}
+	}
+	input := onnxValue{dims: []int64{int64(len(in)), int64(len(in[0]))}}
+	for _, row := range in {
+		input.data = append(input.data, row...)
+	}
+	values[m.Graph.Inputs[0].Name] = input
+	for _, node := range m.Graph.Nodes {
+		args := make([]onnxValue, len(node.Inputs))
+		for k, name := range node.Inputs {
+			v, ok := values[name]
+			if !ok {
+				return nil, fmt.Errorf("%s: missing input %s", node.Name, name)
+			}
+			args[k] = v
+		}
+		outs, err := evalONNXNode(node, args)
+		if err != nil {
+			return nil, err
+		}
+		for k, out := range outs {
+			values[node.Outputs[k]] = out
+		}
+	}
+	out := values[m.Graph.Outputs[0].Name]
+	cols := int(out.dims[len(out.dims)-1])
+	result := make([][]float64, len(out.data)/cols)
+	for k := range result {
+		result[k] = out.data[k*cols : (k+1)*cols]
+	}
+	return result, nil
+}
+
+func evalONNXNode(node onnxNode, args []onnxValue) ([]onnxValue, error) {
+	a := args[0]
+	out := onnxValue{dims: a.dims, data: make([]float64, len(a.data))}
+	// Element-wise operators broadcast their second operand over the trailing dimensions
+	binary := map[string]func(x, y float64) float64{
+		"Add": func(x, y float64) float64 { return x + y },
+		"Sub": func(x, y float64) float64 { return x - y },
+		"Mul": func(x, y float64) float64 { return x * y },
+		"Div": func(x, y float64) float64 { return x / y },
+	}
+	if f, ok := binary[node.OpType]; ok {
+		b := args[1]
+		for k, v := range a.data {
+			out.data[k] = f(v, b.data[k%len(b.data)])
+		}
+		return []onnxValue{out}, nil
+	}
+	switch node.OpType {
+	case "Gemm":
+		w, bias := args[1], args[2]
+		rows, inner, cols := int(a.dims[0]), int(w.dims[0]), int(w.dims[1])
+		out = onnxValue{dims: []int64{int64(rows), int64(cols)}, data: make([]float64, rows*cols)}
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				var sum float64
+				for k := 0; k < inner; k++ {
+					sum += a.data[r*inner+k] * w.data[k*cols+c]
+				}
+				out.data[r*cols+c] = sum + bias.data[c]
+			}
+		}
+	case "Sigmoid":
+		for k, v := range a.data {
+			out.data[k] = 1 / (1 + math.Exp(-v))
+		}
+	case "Clip":
+		for k, v := range a.data {
+			out.data[k] = math.Min(math.Max(v, args[1].data[0]), args[2].data[0])
+		}
+	case "Reshape":
+		out.data = a.data
+		out.dims = make([]int64, len(args[1].data))
+		for k, v := range args[1].data {
+			out.dims[k] = int64(v)
+			if v == 0 {
+				out.dims[k] = a.dims[k]
+			}
+		}
+	case "Softmax":
+		size := int(a.dims[len(a.dims)-1])
+		for s := 0; s < len(a.data); s += size {
+			max := math.Inf(-1)
+			for _, v := range a.data[s : s+size] {
+				max = math.Max(max, v)
+			}
+			var sum float64
+			for k, v := range a.data[s : s+size] {
+				out.data[s+k] = math.Exp(v - max)
+				sum += out.data[s+k]
+			}
+			for k := range a.data[s : s+size] {
+				out.data[s+k] /= sum
+			}
+		}
+	case "Split":
+		// Split the columns of a matrice
+		rows, cols := int(a.dims[0]), int(a.dims[1])
+		outs := make([]onnxValue, len(args[1].data))
+		start := 0
+		for k, size := range args[1].data {
+			outs[k] = onnxValue{dims: []int64{int64(rows), int64(size)}}
+			for r := 0; r < rows; r++ {
+				outs[k].data = append(outs[k].data, a.data[r*cols+start:r*cols+start+int(size)]...)
+			}
+			start += int(size)
+		}
+		return outs, nil
+	case "Concat":
+		// Concatenate the columns of matrices
+		rows, cols := int(a.dims[0]), 0
+		for _, arg := range args {
+			cols += int(arg.dims[1])
+		}
+		out = onnxValue{dims: []int64{int64(rows), int64(cols)}}
+		for r := 0; r < rows; r++ {
+			for _, arg := range args {
+				size := int(arg.dims[1])
+				out.data = append(out.data, arg.data[r*size:(r+1)*size]...)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported operator %s", node.Name, node.OpType)
+	}
+	return []onnxValue{out}, nil
+}
+
+// onnxNormalization is exported as the Sub and Div nodes on the graph input
+var onnxNormalization = &Normalization{
+	Mean: []float64{0.5, -1, 2},
+	Std:  []float64{2, 0.5, 1},
+}
+
+func TestONNXExport(t *testing.T) {
+	in := [][]float64{{0.1, -4, 2}, {7, 0.3, -0.5}, {-1, 1, 9}}
+	for _, precision := range []string{"float64", "float32", "uneven"} {
+		n := testNetwork(t, NetData{Normalization: onnxNormalization})
+		if precision == "uneven" {
+			n = testNetwork(t, NetData{SoftmaxGroups: [][]int{nil, nil, {1, 3}}, Normalization: onnxNormalization})
+		} else {
+			n.Precision = precision
+		}
+		if err := n.Forward(in); err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		if err := n.EncodeONNX(buf); err != nil {
+			t.Fatal(err)
+		}
+		m, err := parseONNX(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Opsets[0].Version != onnxOpsetVersion || m.Graph.Inputs[0].Dims[1] != 3 || m.Graph.Outputs[0].Dims[1] != 4 {
+			t.Errorf("%s: unexpected model description", precision)
+		}
+		got, err := evalONNX(m, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tolerance := 1e-12
+		if precision == "float32" {
+			tolerance = 1e-5
+		}
+		for k, row := range n.GetOutput() {
+			for i, v := range row {
+				if math.Abs(got[k][i]-v) > tolerance {
+					t.Errorf("%s: output [%d][%d]: got %v, want %v", precision, k, i, got[k][i], v)
+				}
+			}
+		}
+	}
+}
+
+func TestExportONNXFile(t *testing.T) {
+	n := testNetwork(t, NetData{Normalization: onnxNormalization})
+	path := filepath.Join(t.TempDir(), "model.onnx")
+	if err := n.ExportONNX(path); err != nil {
+		t.Fatal(err)
+	}
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := parseONNX(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gemms := 0
+	for _, node := range m.Graph.Nodes {
+		if node.OpType == "Gemm" {
+			gemms++
+		}
+	}
+	if gemms != len(n.Layers) {
+		t.Errorf("Got %d Gemm nodes, want %d", gemms, len(n.Layers))
+	}
+}
+
+func TestONNXImportRoundTrip(t *testing.T) {
+	in := [][]float64{{0.1, -4, 2}, {7, 0.3, -0.5}, {-1, 1, 9}}
+	for _, groups := range [][]int{{2, 2}, {3, 1}} {
+		testONNXRoundTrip(t, testNetwork(t, NetData{SoftmaxGroups: [][]int{nil, nil, groups}, Normalization: onnxNormalization}), in, groups)
+	}
+}
+
+func testONNXRoundTrip(t *testing.T, n *Network, in [][]float64, groups []int) {
+	if err := n.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := n.EncodeONNX(buf); err != nil {
+		t.Fatal(err)
+	}
+	y, err := DecodeONNX(buf, 3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(y.SoftmaxGroups[2]) != fmt.Sprint(groups) || y.Normalization == nil || y.Activations[0] != "tanh" {
+		t.Fatalf("Network settings were not imported, softmax groups %v", y.SoftmaxGroups)
+	}
+	assertSameWeights(t, exportWeights(t, n), exportWeights(t, y))
+	if err := y.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	want := n.GetOutput()
+	for k, row := range y.GetOutput() {
+		for i, v := range row {
+			if v != want[k][i] {
+				t.Errorf("Output [%d][%d]: got %v, want %v", k, i, v, want[k][i])
+			}
+		}
+	}
+}
+
+func TestONNXZeroStd(t *testing.T) {
+	n, err := New(NetData{
+		Nodes:         []int{2, 4, 2},
+		Activations:   []string{"sigmoid", "softmax"},
+		BatchSize:     2,
+		Normalization: &Normalization{Mean: []float64{1, 2}, Std: []float64{2, 0}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := [][]float64{{3, 5}, {-1, 0.5}}
+	if err := n.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	want := n.GetOutput()
+	buf := &bytes.Buffer{}
+	if err := n.EncodeONNX(buf); err != nil {
+		t.Fatal(err)
+	}
+	m, err := parseONNX(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := evalONNX(m, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := DecodeONNX(bytes.NewReader(buf.Bytes()), 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := y.Forward(in); err != nil {
+		t.Fatal(err)
+	}
+	for k, row := range want {
+		for i, v := range row {
+			if math.Abs(got[k][i]-v) > 1e-12 || math.IsNaN(got[k][i]) {
+				t.Errorf("ONNX output [%d][%d]: got %v, want %v", k, i, got[k][i], v)
+			}
+			if math.Abs(y.GetOutput()[k][i]-v) > 1e-12 {
+				t.Errorf("Imported output [%d][%d]: got %v, want %v", k, i, y.GetOutput()[k][i], v)
+			}
+		}
+	}
+}
+
+// onnxTestModel builds a 2-3-2 network as a transposed Gemm with Tanh and a MatMul, Add and Softmax
+func onnxTestModel(ops ...string) onnxModel {
+	g := onnxGraph{
+		Inputs:  []onnxValueInfo{{Name: "x", ElemType: onnxFloat, Dims: []int64{-1, 2}}},
+		Outputs: []onnxValueInfo{{Name: "y", ElemType: onnxFloat, Dims: []int64{-1, 2}}},
+		Initializers: []onnxTensor{
+			{Name: "w1", DataType: onnxFloat, Dims: []int64{3, 2}, Values: []float64{1, 2, 3, 4, 5, 6}},
+			{Name: "b1", DataType: onnxFloat, Dims: []int64{3}, Values: []float64{0.5, 0, -0.5}},
+			{Name: "w2", DataType: onnxFloat, Dims: []int64{3, 2}, Values: []float64{1, -1, 0, 2, 0.5, 0}},
+			{Name: "b2", DataType: onnxFloat, Dims: []int64{2}, Values: []float64{0.25, -0.25}},
+		},
+		Nodes: []onnxNode{
+			{OpType: "Gemm", Inputs: []string{"x", "w1", "b1"}, Outputs: []string{"h"}, Attributes: []onnxAttribute{{Name: "transB", Type: onnxAttrInt, I: 1}}},
+			{OpType: ops[0], Inputs: []string{"h"}, Outputs: []string{"a"}},
+			{OpType: "MatMul", Inputs: []string{"a", "w2"}, Outputs: []string{"m"}},
+			{OpType: "Add", Inputs: []string{"b2", "m"}, Outputs: []string{"z"}},
+			{OpType: ops[1], Inputs: []string{"z"}, Outputs: []string{"y"}},
+		},
+	}
+	return onnxModel{IRVersion: onnxIRVersion, Opsets: []onnxOpset{{Version: onnxOpsetVersion}}, Graph: g}
+}
+
+func TestONNXImport(t *testing.T) {
+	p := &protoWriter{}
+	m := onnxTestModel("Tanh", "Softmax")
+	m.marshal(p)
+	n, err := DecodeONNX(bytes.NewReader(p.buf), 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.InputCount != 2 || n.Layers[0].NodesCount != 3 || n.SoftmaxGroups[1] != nil || n.Precision != "float32" || !n.isTrain {
+		t.Fatal("Network structure was not imported")
+	}
+	// The transposed Gemm weights are stored as inputs x nodes
+	weights := n.netData().WeightsData
+	want := []float64{1, 3, 5, 2, 4, 6}
+	for k, v := range want {
+		if weights[0].Weights[k] != v {
+			t.Errorf("Weight %d: got %v, want %v", k, weights[0].Weights[k], v)
+		}
+	}
+	if weights[1].BiasWeights[0] != 0.25 {
+		t.Error("Bias of the Add node was not imported")
+	}
+
+	for _, ops := range [][]string{{"Elu", "Softmax"}, {"Tanh", "Conv"}} {
+		p := &protoWriter{}
+		m := onnxTestModel(ops...)
+		m.marshal(p)
+		_, err := DecodeONNX(bytes.NewReader(p.buf), 1, false)
+		if err == nil || !strings.Contains(err.Error(), ERROR_ONNX_OPERATOR) {
+			t.Errorf("%v: expected an unsupported operator error, got %v", ops, err)
+		}
+	}
+	m = onnxTestModel("Tanh", "Softmax")
+	m.Graph.Nodes[2].Inputs[0] = "h"
+	p = &protoWriter{}
+	m.marshal(p)
+	if _, err := DecodeONNX(bytes.NewReader(p.buf), 1, false); err == nil {
+		t.Error("Expected an error for a graph that is not a chain")
+	}
+}
+
+// Truncated and miswired graphs are rejected with ErrONNXGraph instead of panicking
+func TestONNXMalformedGraph(t *testing.T) {
+	for _, groups := range [][]int{nil, {2, 2}, {3, 1}} {
+		buf := &bytes.Buffer{}
+		if err := testNetwork(t, NetData{SoftmaxGroups: [][]int{nil, nil, groups}, Normalization: onnxNormalization}).EncodeONNX(buf); err != nil {
+			t.Fatal(err)
+		}
+		parse := func() *onnxModel {
+			m, err := parseONNX(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return &m
+		}
+		count := len(parse().Graph.Nodes)
+		for k := 0; k < count; k++ {
+			m := parse()
+			m.Graph.Nodes = m.Graph.Nodes[:k]
+			if _, err := m.netData(); !errors.Is(err, ErrONNXGraph) {
+				t.Errorf("Groups %v, graph truncated to %d nodes: got %v", groups, k, err)
+			}
+			for _, change := range []string{"inputs", "outputs", "input", "output"} {
+				m := parse()
+				node := &m.Graph.Nodes[k]
+				switch change {
+				case "inputs":
+					node.Inputs = nil
+				case "outputs":
+					node.Outputs = nil
+				case "input":
+					node.Inputs[len(node.Inputs)-1] = "other"
+				case "output":
+					node.Outputs[0] = "other"
+				}
+				if _, err := m.netData(); !errors.Is(err, ErrONNXGraph) {
+					t.Errorf("Groups %v, %s of node %d %s changed: got %v", groups, change, k, node.OpType, err)
+				}
+			}
+		}
+	}
+	m := onnxTestModel("Tanh", "Softmax")
+	m.Graph.Initializers[0].Dims = []int64{-3, -2}
+	if _, err := m.netData(); !errors.Is(err, ErrONNXGraph) {
+		t.Errorf("Negative weight dimensions: got %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := testNetwork(t, NetData{SoftmaxGroups: [][]int{nil, nil, {2, 2}}, Normalization: onnxNormalization}).EncodeONNX(buf); err != nil {
+		t.Fatal(err)
+	}
+	// The group count must match the layer size instead of being trusted
+	for _, shape := range [][]int64{{0, 1 << 40, 1}, {0, 1 << 62, 1 << 2}, {0, 4, 2}} {
+		m, err := parseONNX(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		inits := map[string]*onnxTensor{}
+		for k := range m.Graph.Initializers {
+			inits[m.Graph.Initializers[k].Name] = &m.Graph.Initializers[k]
+		}
+		for _, node := range m.Graph.Nodes {
+			if node.OpType == "Reshape" {
+				inits[node.Inputs[1]].Int64s = shape
+				break
+			}
+		}
+		if _, err := m.netData(); !errors.Is(err, ErrONNXGraph) {
+			t.Errorf("Reshape to %v: got %v", shape, err)
+		}
+	}
+	// Div then Sub computes x/std - mean, which is not a Normalization
+	m, err := parseONNX(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := m.Graph.Nodes
+	nodes[0].OpType, nodes[1].OpType = "Div", "Sub"
+	nodes[0].Inputs[1], nodes[1].Inputs[1] = nodes[1].Inputs[1], nodes[0].Inputs[1]
+	if _, err := m.netData(); !errors.Is(err, ErrONNXGraph) {
+		t.Errorf("Div before Sub: got %v", err)
+	}
+}
+
+func TestONNXRawData(t *testing.T) {
+	raw := appendFixed32(appendFixed32(nil, math.Float32bits(1.5)), math.Float32bits(-2))
+	p := &protoWriter{}
+	p.packedVarints(1, []int64{2})
+	p.varint(2, onnxFloat)
+	p.string(8, "w")
+	p.bytes(9, raw)
+	tensor, err := parseONNXTensor(p.buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tensor.Name != "w" || len(tensor.Values) != 2 || tensor.Values[0] != 1.5 || tensor.Values[1] != -2 {
+		t.Errorf("Unexpected tensor %+v", tensor)
+	}
+}
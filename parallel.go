@@ -0,0 +1,124 @@
+package neuro
+
+import (
+	"runtime"
+	"sync"
+)
+
+// ParallelTrainer trains a network data-parallel. Every mini-batch is split
+// between worker replicas that share the network's weights, the gradients are
+// computed concurrently and summed, and a single update is applied to the network.
+// The result matches Backward on the whole batch up to the floating-point order.
+//
+// The replicas copy the network's Preprocessing and Normalization when the
+// trainer is created, so a trainer must be created again after they change.
+// Step only updates the network's weights, its layer outputs are left from
+// its last Forward call.
+type ParallelTrainer struct {
+	Net      *Network
+	replicas []*Network
+	bounds   []int
+}
+
+// NewParallelTrainer returns a trainer for the network using the given number of workers.
+// With workers <= 0 GOMAXPROCS workers are used, and there are never more workers than batch rows
+func NewParallelTrainer(n *Network, workers int) (*ParallelTrainer, error) {
+	if !n.isTrain {
+		return nil, ErrNotTrainable
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n.BatchSize {
+		workers = n.BatchSize
+	}
+	t := &ParallelTrainer{
+		Net:      n,
+		replicas: make([]*Network, workers),
+		bounds:   make([]int, workers+1),
+	}
+	nodes := make([]int, len(n.Layers)+1)
+	nodes[0] = n.InputCount
+	for k := range n.Layers {
+		nodes[k+1] = n.Layers[k].NodesCount
+	}
+	for w := range t.replicas {
+		t.bounds[w+1] = (w + 1) * n.BatchSize / workers
+		r, err := New(NetData{
+			Nodes:         nodes,
+			Activations:   n.Activations,
+			BatchSize:     t.bounds[w+1] - t.bounds[w],
+			Train:         true,
+			SoftmaxGroups: n.SoftmaxGroups,
+			Heads:         n.Heads,
+			Normalization: n.Normalization,
+			Preprocessing: n.Preprocessing,
+		})
+		if err != nil {
+			return nil, err
+		}
+		t.replicas[w] = r
+	}
+	return t, nil
+}
+
+// Step runs Forward and Backward for a batch across the workers. A batch
+// shorter than the batch size, such as the last one of a dataset, fills the
+// first workers and leaves the others idle
+func (t *ParallelTrainer) Step(in, target [][]float64) error {
+	n := t.Net
+	if len(in) == 0 || len(in) > n.BatchSize || len(target) != len(in) {
+		return ErrWrongBatchCount
+	}
+	if n.LearnRate <= 0.0 {
+		return ErrLearnRate
+	}
+	// Every worker takes as many rows as its replica holds
+	workers := 0
+	bounds := []int{0}
+	for bounds[workers] < len(in) {
+		end := bounds[workers] + t.bounds[workers+1] - t.bounds[workers]
+		if end > len(in) {
+			end = len(in)
+		}
+		bounds = append(bounds, end)
+		workers++
+	}
+	replicas := t.replicas[:workers]
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w, r := range replicas {
+		// The replicas only read the weights until all the workers are done
+		for k := range r.Layers {
+			r.Layers[k].Weights = n.Layers[k].Weights
+			r.Layers[k].BiasWeights = n.Layers[k].BiasWeights
+		}
+		r.Strict = n.Strict
+		wg.Add(1)
+		go func(w int, r *Network, in, target [][]float64) {
+			defer wg.Done()
+			if err := r.Forward(in); err != nil {
+				errs[w] = err
+				return
+			}
+			errs[w] = r.gradients(target)
+		}(w, r, in[bounds[w]:bounds[w+1]], target[bounds[w]:bounds[w+1]])
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	// Sum the gradients of the replicas in to the network
+	for k := range n.Layers {
+		n.Layers[k].DeltaWeights.Copy(replicas[0].Layers[k].DeltaWeights)
+		n.Layers[k].DeltaBias.CopyVec(replicas[0].Layers[k].DeltaBias)
+		for _, r := range replicas[1:] {
+			n.Layers[k].DeltaWeights.Add(n.Layers[k].DeltaWeights, r.Layers[k].DeltaWeights)
+			n.Layers[k].DeltaBias.AddVec(n.Layers[k].DeltaBias, r.Layers[k].DeltaBias)
+		}
+	}
+	n.update()
+	return nil
+}
@@ -0,0 +1,80 @@
+package neuro
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParallelTrainer(t *testing.T) {
+	data := NetData{
+		Nodes:       []int{3, 8, 5, 2},
+		Activations: []string{"tanh", "tanh", "sigmoid"},
+		BatchSize:   6,
+		Train:       true,
+	}
+	single, err := New(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.WeightsData = exportWeights(t, single)
+	parallel, err := New(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []*Network{single, parallel} {
+		n.LearnRate = 0.1
+		n.Momentum = 0.5
+	}
+	trainer, err := NewParallelTrainer(parallel, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := [][]float64{
+		[]float64{1, 1, 0}, []float64{0, 1, 1}, []float64{1, 0, 1},
+		[]float64{0, 0, 1}, []float64{1, 1, 1}, []float64{0, 1, 0},
+	}
+	target := [][]float64{
+		[]float64{1, 0}, []float64{0, 1}, []float64{0, 1},
+		[]float64{1, 0}, []float64{1, 0}, []float64{0, 1},
+	}
+	// The short batches at the end leave some of the 4 workers idle
+	for i := 0; i < 22; i++ {
+		rows := len(in)
+		switch i {
+		case 20:
+			rows = 5
+		case 21:
+			rows = 3
+		}
+		if err := single.Forward(in[:rows]); err != nil {
+			t.Fatal(err)
+		}
+		if err := single.Backward(target[:rows]); err != nil {
+			t.Fatal(err)
+		}
+		if err := trainer.Step(in[:rows], target[:rows]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ws, wp := exportWeights(t, single), exportWeights(t, parallel)
+	for k := range ws {
+		for i := range ws[k].Weights {
+			if math.Abs(ws[k].Weights[i]-wp[k].Weights[i]) > 1e-9 {
+				t.Errorf("Layer %d weight %d: got %v, want %v", k, i, wp[k].Weights[i], ws[k].Weights[i])
+			}
+		}
+		for i := range ws[k].BiasWeights {
+			if math.Abs(ws[k].BiasWeights[i]-wp[k].BiasWeights[i]) > 1e-9 {
+				t.Errorf("Layer %d bias %d: got %v, want %v", k, i, wp[k].BiasWeights[i], ws[k].BiasWeights[i])
+			}
+		}
+	}
+	for _, rows := range [][2]int{{0, 0}, {3, 2}, {7, 7}} {
+		in := append(in, in...)
+		target := append(target, target...)
+		if err := trainer.Step(in[:rows[0]], target[:rows[1]]); !errors.Is(err, ErrWrongBatchCount) {
+			t.Errorf("%d input and %d target rows: got %v, want ErrWrongBatchCount", rows[0], rows[1], err)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package neuro
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+type (
+	// Preprocessor is a step of a preprocessing Pipeline, fitted on the
+	// training rows and then applied to every input row. Kind selects the step:
+	//
+	//	"standard" scales the columns to a mean of 0 and a standard deviation of 1
+	//	"minmax"   scales the columns to [0, 1]
+	//	"robust"   centers the columns on their median and scales them by the interquartile range
+	//	"impute"   replaces NaN values with the mean, the median or a constant
+	//	"onehot"   replaces categorical columns by one-hot columns appended to the row
+	//
+	// The scalers ignore NaN values when fitting and leave them as they are.
+	Preprocessor struct {
+		Kind string
+		// Columns are the indexes of the columns the step changes, all of them when empty
+		Columns []int `json:",omitempty"`
+		// Strategy of an "impute" step, "mean", "median" or "constant" with Value
+		Strategy string  `json:",omitempty"`
+		Value    float64 `json:",omitempty"`
+		// Inputs is the width of the rows read by the step, set by Fit
+		Inputs int
+		// The fitted values of every column, the scalers store (v - Center) / Scale
+		Center     []float64   `json:",omitempty"`
+		Scale      []float64   `json:",omitempty"`
+		Fill       []float64   `json:",omitempty"`
+		Categories [][]float64 `json:",omitempty"`
+		// cols are the resolved Columns and encoded marks them, set by fit and validate
+		cols    []int
+		encoded []bool
+	}
+	// Pipeline chains preprocessing steps, every step reads the rows written by the previous one
+	Pipeline []Preprocessor
+)
+
+// StandardScaler returns a step scaling the columns to a mean of 0 and a standard deviation of 1
+func StandardScaler(columns ...int) Preprocessor {
+	return Preprocessor{Kind: "standard", Columns: columns}
+}
+
+// MinMaxScaler returns a step scaling the columns to [0, 1]
+func MinMaxScaler(columns ...int) Preprocessor {
+	return Preprocessor{Kind: "minmax", Columns: columns}
+}
+
+// RobustScaler returns a step centering the columns on their median, scaled by the interquartile range
+func RobustScaler(columns ...int) Preprocessor {
+	return Preprocessor{Kind: "robust", Columns: columns}
+}
+
+// OneHotEncoder returns a step replacing categorical columns by one-hot columns
+// of their values, appended after the other columns. Unknown values give zeros
+func OneHotEncoder(columns ...int) Preprocessor {
+	return Preprocessor{Kind: "onehot", Columns: columns}
+}
+
+// Imputer returns a step replacing NaN values with the "mean" or the "median" of their column
+func Imputer(strategy string, columns ...int) Preprocessor {
+	return Preprocessor{Kind: "impute", Strategy: strategy, Columns: columns}
+}
+
+// ConstantImputer returns a step replacing NaN values with value
+func ConstantImputer(value float64, columns ...int) Preprocessor {
+	return Preprocessor{Kind: "impute", Strategy: "constant", Value: value, Columns: columns}
+}
+
+// clone returns a deep copy of the pipeline
+func (p Pipeline) clone() Pipeline {
+	if p == nil {
+		return nil
+	}
+	c := make(Pipeline, len(p))
+	for k, s := range p {
+		s.Columns = append([]int(nil), s.Columns...)
+		s.Center = append([]float64(nil), s.Center...)
+		s.Scale = append([]float64(nil), s.Scale...)
+		s.Fill = append([]float64(nil), s.Fill...)
+		if s.Categories != nil {
+			s.Categories = make([][]float64, len(p[k].Categories))
+			for i, values := range p[k].Categories {
+				s.Categories[i] = append([]float64(nil), values...)
+			}
+		}
+		c[k] = s
+	}
+	return c
+}
+
+// transformBatch transforms the input rows of an inference or training
+// batch, more rows than the batch size are an error
+func (p Pipeline) transformBatch(in [][]float64, batchSize int) ([][]float64, error) {
+	if len(in) > batchSize {
+		return nil, fmt.Errorf("%w: got %d rows, batch size %d", ErrWrongBatchCount, len(in), batchSize)
+	}
+	return p.Transform(in)
+}
+
+// Fit fits every step on the rows transformed by the previous steps
+func (p Pipeline) Fit(rows [][]float64) error {
+	if len(p) == 0 || len(rows) == 0 {
+		return ErrPreprocessing
+	}
+	for k := range p {
+		if err := p[k].fit(rows); err != nil {
+			return err
+		}
+		next := make([][]float64, len(rows))
+		for i, row := range rows {
+			next[i] = p[k].transform(row)
+		}
+		rows = next
+	}
+	return nil
+}
+
+// Transform returns the preprocessed copies of the rows
+func (p Pipeline) Transform(rows [][]float64) ([][]float64, error) {
+	if len(p) == 0 {
+		return rows, nil
+	}
+	out := make([][]float64, len(rows))
+	for i, row := range rows {
+		if len(row) != p.Inputs() {
+			return nil, &ShapeError{Err: ErrWrongInputsCount, Layer: -1, Want: []int{len(rows), p.Inputs()}, Got: []int{len(rows), len(row)}}
+		}
+		out[i] = row
+		for k := range p {
+			out[i] = p[k].transform(out[i])
+		}
+	}
+	return out, nil
+}
+
+// Inputs returns the width of the rows read by the pipeline
+func (p Pipeline) Inputs() int {
+	if len(p) == 0 {
+		return 0
+	}
+	return p[0].Inputs
+}
+
+// Outputs returns the width of the rows written by the pipeline
+func (p Pipeline) Outputs() int {
+	if len(p) == 0 {
+		return 0
+	}
+	return p[len(p)-1].outputs()
+}
+
+// validate checks that the fitted steps follow each other
+func (p Pipeline) validate() error {
+	for k := range p {
+		s := &p[k]
+		if s.Inputs < 1 || k > 0 && s.Inputs != p[k-1].outputs() {
+			return ErrPreprocessing
+		}
+		if err := s.resolve(); err != nil {
+			return err
+		}
+		var fitted int
+		switch s.Kind {
+		case "standard", "minmax", "robust":
+			fitted = len(s.Center)
+			if len(s.Scale) != fitted {
+				return ErrPreprocessing
+			}
+		case "impute":
+			fitted = len(s.Fill)
+		case "onehot":
+			fitted = len(s.Categories)
+		default:
+			return fmt.Errorf("%w: unknown step %q", ErrPreprocessing, s.Kind)
+		}
+		if fitted != len(s.cols) {
+			return ErrPreprocessing
+		}
+	}
+	return nil
+}
+
+// columns returns the indexes of the columns changed by the step
+func (s *Preprocessor) columns() []int {
+	if len(s.Columns) > 0 {
+		return s.Columns
+	}
+	columns := make([]int, s.Inputs)
+	for k := range columns {
+		columns[k] = k
+	}
+	return columns
+}
+
+// resolve sets the columns of the step once, so transform does not build
+// them for every row. A column out of the row or listed twice is an error
+func (s *Preprocessor) resolve() error {
+	columns := s.columns()
+	encoded := make([]bool, s.Inputs)
+	for _, c := range columns {
+		if c < 0 || c >= s.Inputs {
+			return fmt.Errorf("%w: column %d of %d", ErrPreprocessing, c, s.Inputs)
+		}
+		if encoded[c] {
+			return fmt.Errorf("%w: column %d is listed twice", ErrPreprocessing, c)
+		}
+		encoded[c] = true
+	}
+	s.cols, s.encoded = columns, encoded
+	return nil
+}
+
+// outputs returns the width of the rows written by the step
+func (s *Preprocessor) outputs() int {
+	if s.Kind != "onehot" {
+		return s.Inputs
+	}
+	width := s.Inputs - len(s.Categories)
+	for _, c := range s.Categories {
+		width += len(c)
+	}
+	return width
+}
+
+// fit computes the values of the step from the rows
+func (s *Preprocessor) fit(rows [][]float64) error {
+	s.Inputs = len(rows[0])
+	for _, row := range rows {
+		if len(row) != s.Inputs {
+			return &ShapeError{Err: ErrPreprocessing, Layer: -1, Want: []int{len(rows), s.Inputs}, Got: []int{len(rows), len(row)}}
+		}
+	}
+	if err := s.resolve(); err != nil {
+		return err
+	}
+	s.Center, s.Scale, s.Fill, s.Categories = nil, nil, nil, nil
+	for _, c := range s.cols {
+		// The values of the column without the missing ones, sorted
+		values := make([]float64, 0, len(rows))
+		for _, row := range rows {
+			if !math.IsNaN(row[c]) {
+				values = append(values, row[c])
+			}
+		}
+		sort.Float64s(values)
+		switch s.Kind {
+		case "standard":
+			mean := meanOf(values)
+			var variance float64
+			for _, v := range values {
+				variance += (v - mean) * (v - mean)
+			}
+			if len(values) > 0 {
+				variance /= float64(len(values))
+			}
+			s.Center = append(s.Center, mean)
+			s.Scale = append(s.Scale, math.Sqrt(variance))
+		case "minmax":
+			min, max := 0.0, 0.0
+			if len(values) > 0 {
+				min, max = values[0], values[len(values)-1]
+			}
+			s.Center = append(s.Center, min)
+			s.Scale = append(s.Scale, max-min)
+		case "robust":
+			s.Center = append(s.Center, percentile(values, 0.5))
+			s.Scale = append(s.Scale, percentile(values, 0.75)-percentile(values, 0.25))
+		case "impute":
+			switch s.Strategy {
+			case "mean", "":
+				s.Fill = append(s.Fill, meanOf(values))
+			case "median":
+				s.Fill = append(s.Fill, percentile(values, 0.5))
+			case "constant":
+				s.Fill = append(s.Fill, s.Value)
+			default:
+				return fmt.Errorf("%w: unknown strategy %q", ErrPreprocessing, s.Strategy)
+			}
+		case "onehot":
+			var categories []float64
+			for k, v := range values {
+				if k == 0 || v != values[k-1] {
+					categories = append(categories, v)
+				}
+			}
+			s.Categories = append(s.Categories, categories)
+		default:
+			return fmt.Errorf("%w: unknown step %q", ErrPreprocessing, s.Kind)
+		}
+	}
+	return nil
+}
+
+// transform returns a new row written by the step
+func (s *Preprocessor) transform(row []float64) []float64 {
+	columns, encoded := s.cols, s.encoded
+	if len(encoded) != s.Inputs {
+		// A step that was neither fitted nor validated, such as a decoded one
+		columns = s.columns()
+		encoded = make([]bool, s.Inputs)
+		for _, c := range columns {
+			encoded[c] = true
+		}
+	}
+	if s.Kind == "onehot" {
+		out := make([]float64, 0, s.outputs())
+		for c, v := range row {
+			if !encoded[c] {
+				out = append(out, v)
+			}
+		}
+		for k, c := range columns {
+			for _, category := range s.Categories[k] {
+				if row[c] == category {
+					out = append(out, 1)
+				} else {
+					out = append(out, 0)
+				}
+			}
+		}
+		return out
+	}
+	out := append([]float64(nil), row...)
+	for k, c := range columns {
+		switch s.Kind {
+		case "impute":
+			if math.IsNaN(out[c]) {
+				out[c] = s.Fill[k]
+			}
+		default:
+			out[c] -= s.Center[k]
+			if s.Scale[k] != 0 {
+				out[c] /= s.Scale[k]
+			}
+		}
+	}
+	return out
+}
+
+// meanOf returns the mean of the values, 0 without values
+func meanOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// percentile returns the q quantile of sorted values with linear interpolation, 0 without values
+func percentile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := q * float64(len(sorted)-1)
+	low := int(pos)
+	if low+1 >= len(sorted) {
+		return sorted[low]
+	}
+	return sorted[low] + (pos-float64(low))*(sorted[low+1]-sorted[low])
+}
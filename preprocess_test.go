@@ -0,0 +1,162 @@
+package neuro
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+// preprocessRows have a numeric column with a missing value, a wide column and a category
+var preprocessRows = [][]float64{
+	{1, 100, 2},
+	{math.NaN(), 300, 1},
+	{3, 200, 2},
+	{5, 1000, 3},
+}
+
+func TestPipeline(t *testing.T) {
+	p := Pipeline{Imputer("median", 0), OneHotEncoder(2), MinMaxScaler(0), RobustScaler(1)}
+	if err := p.Fit(preprocessRows); err != nil {
+		t.Fatal(err)
+	}
+	if p.Inputs() != 3 || p.Outputs() != 5 {
+		t.Fatalf("Pipeline of %d inputs and %d outputs, want 3 and 5", p.Inputs(), p.Outputs())
+	}
+	out, err := p.Transform(preprocessRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The median 3 fills the missing value, min-max gives (3 - 1) / 4, the
+	// robust scaler (300 - 250) / (475 - 175) and category 1 is the first one-hot column
+	want := []float64{0.5, 1.0 / 6, 1, 0, 0}
+	for k, v := range want {
+		if math.Abs(out[1][k]-v) > 1e-12 {
+			t.Errorf("Transformed row %v, want %v", out[1], want)
+			break
+		}
+	}
+	if !math.IsNaN(preprocessRows[1][0]) {
+		t.Error("Transform changed the rows")
+	}
+
+	s := Pipeline{StandardScaler()}
+	if err := s.Fit([][]float64{{1, 5}, {3, 5}}); err != nil {
+		t.Fatal(err)
+	}
+	out, err = s.Transform([][]float64{{3, 7}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[0][0] != 1 || out[0][1] != 2 {
+		t.Errorf("Standard scaled row %v, want [1 2]", out[0])
+	}
+	if err := (Pipeline{Imputer("mode")}).Fit(preprocessRows); !errors.Is(err, ErrPreprocessing) {
+		t.Errorf("Expected ErrPreprocessing for an unknown strategy, got %v", err)
+	}
+	// A column listed twice would be encoded or scaled twice
+	for _, step := range []Preprocessor{OneHotEncoder(2, 2), StandardScaler(0, 1, 0)} {
+		if err := (Pipeline{step}).Fit(preprocessRows); !errors.Is(err, ErrPreprocessing) {
+			t.Errorf("%s %v: expected ErrPreprocessing for a duplicate column, got %v", step.Kind, step.Columns, err)
+		}
+	}
+	fitted := Pipeline{MinMaxScaler(0, 1)}
+	if err := fitted.Fit(preprocessRows); err != nil {
+		t.Fatal(err)
+	}
+	fitted[0].Columns = []int{1, 1}
+	if err := fitted.validate(); !errors.Is(err, ErrPreprocessing) {
+		t.Errorf("Expected ErrPreprocessing for a fitted step with a duplicate column, got %v", err)
+	}
+}
+
+func TestPreprocessingModel(t *testing.T) {
+	p := Pipeline{ConstantImputer(0), OneHotEncoder(2), StandardScaler(0, 1)}
+	if err := p.Fit(preprocessRows); err != nil {
+		t.Fatal(err)
+	}
+	data := NetData{Nodes: []int{5, 4, 2}, Activations: []string{"tanh", "softmax"}, BatchSize: 4, Preprocessing: p, Seed: 4}
+	n, err := New(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := n.Encode(buf); err != nil {
+		t.Fatal(err)
+	}
+	y, err := Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The raw rows give the output of the preprocessed rows without the pipeline
+	data.Preprocessing = nil
+	data.WeightsData = y.netData().WeightsData
+	plain, err := New(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := y.Forward(preprocessRows); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := p.Transform(preprocessRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plain.Forward(rows); err != nil {
+		t.Fatal(err)
+	}
+	for k, row := range plain.GetOutput() {
+		for i, v := range row {
+			if got := y.GetOutput()[k][i]; got != v {
+				t.Errorf("Output [%d][%d] is %v, want %v", k, i, got, v)
+			}
+		}
+	}
+
+	// The inference engines apply the pipeline as well
+	c, err := y.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	predicted, err := c.Predict(preprocessRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n32, err := y.Float32()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows32 := make([][]float32, len(preprocessRows))
+	for k, row := range preprocessRows {
+		for _, v := range row {
+			rows32[k] = append(rows32[k], float32(v))
+		}
+	}
+	if err := n32.Forward(rows32); err != nil {
+		t.Fatal(err)
+	}
+	for k, row := range y.GetOutput() {
+		for i, v := range row {
+			if math.Abs(predicted[k][i]-v) > 1e-12 {
+				t.Errorf("Compiled output [%d][%d] is %v, want %v", k, i, predicted[k][i], v)
+			}
+			if got := float64(n32.GetOutput()[k][i]); math.Abs(got-v) > 1e-5 {
+				t.Errorf("Float32 output [%d][%d] is %v, want %v", k, i, got, v)
+			}
+		}
+	}
+
+	var shape *ShapeError
+	if err := y.Forward([][]float64{{1, 2}}); !errors.As(err, &shape) || !errors.Is(err, ErrWrongInputsCount) {
+		t.Errorf("Expected a ShapeError for a raw row of 2 columns, got %v", err)
+	}
+	if err := n.EncodeONNX(&bytes.Buffer{}); !errors.Is(err, ErrPreprocessing) {
+		t.Errorf("Expected ErrPreprocessing from the ONNX export, got %v", err)
+	}
+	data.Nodes[0] = 3
+	data.WeightsData = nil
+	data.Preprocessing = p
+	if _, err := New(data); !errors.Is(err, ErrPreprocessing) {
+		t.Errorf("Expected ErrPreprocessing for 3 inputs, got %v", err)
+	}
+}
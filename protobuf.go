@@ -0,0 +1,193 @@
+package neuro
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Protocol buffers wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// protoWriter appends protocol buffers fields to a buffer
+type protoWriter struct {
+	buf []byte
+}
+
+func (w *protoWriter) tag(field, wire int) {
+	w.buf = appendVarint(w.buf, uint64(field)<<3|uint64(wire))
+}
+
+func (w *protoWriter) varint(field int, v uint64) {
+	w.tag(field, wireVarint)
+	w.buf = appendVarint(w.buf, v)
+}
+
+func (w *protoWriter) bytes(field int, b []byte) {
+	w.tag(field, wireBytes)
+	w.buf = appendVarint(w.buf, uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *protoWriter) string(field int, s string) {
+	w.bytes(field, []byte(s))
+}
+
+func (w *protoWriter) fixed32(field int, v uint32) {
+	w.tag(field, wireFixed32)
+	w.buf = appendFixed32(w.buf, v)
+}
+
+// message writes a nested message built by marshal
+func (w *protoWriter) message(field int, marshal func(*protoWriter)) {
+	m := &protoWriter{}
+	marshal(m)
+	w.bytes(field, m.buf)
+}
+
+func (w *protoWriter) packedVarints(field int, values []int64) {
+	var b []byte
+	for _, v := range values {
+		b = appendVarint(b, uint64(v))
+	}
+	w.bytes(field, b)
+}
+
+func (w *protoWriter) packedFloats(field int, values []float64) {
+	b := make([]byte, 0, 4*len(values))
+	for _, v := range values {
+		b = appendFixed32(b, math.Float32bits(float32(v)))
+	}
+	w.bytes(field, b)
+}
+
+func (w *protoWriter) packedDoubles(field int, values []float64) {
+	b := make([]byte, 0, 8*len(values))
+	for _, v := range values {
+		b = appendFixed64(b, math.Float64bits(v))
+	}
+	w.bytes(field, b)
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendFixed32(b []byte, v uint32) []byte {
+	var tmp [4]byte
+	binary.LittleEndian.PutUint32(tmp[:], v)
+	return append(b, tmp[:]...)
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], v)
+	return append(b, tmp[:]...)
+}
+
+// protoField is a decoded field, v holds varint and fixed values and b the bytes
+type protoField struct {
+	num  int
+	wire int
+	v    uint64
+	b    []byte
+}
+
+// parseProto splits a message in to its fields
+func parseProto(buf []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return nil, ErrProtobuf
+		}
+		buf = buf[n:]
+		f := protoField{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			f.v, n = binary.Uvarint(buf)
+			if n <= 0 {
+				return nil, ErrProtobuf
+			}
+			buf = buf[n:]
+		case wireFixed64:
+			if len(buf) < 8 {
+				return nil, ErrProtobuf
+			}
+			f.v = binary.LittleEndian.Uint64(buf)
+			buf = buf[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(buf)
+			if n <= 0 || uint64(len(buf)-n) < size {
+				return nil, ErrProtobuf
+			}
+			f.b = buf[n : n+int(size)]
+			buf = buf[n+int(size):]
+		case wireFixed32:
+			if len(buf) < 4 {
+				return nil, ErrProtobuf
+			}
+			f.v = uint64(binary.LittleEndian.Uint32(buf))
+			buf = buf[4:]
+		default:
+			return nil, ErrProtobuf
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// varints returns the values of a repeated varint field, packed or not
+func (f protoField) varints() ([]int64, error) {
+	if f.wire == wireVarint {
+		return []int64{int64(f.v)}, nil
+	}
+	var values []int64
+	for b := f.b; len(b) > 0; {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, ErrProtobuf
+		}
+		values = append(values, int64(v))
+		b = b[n:]
+	}
+	return values, nil
+}
+
+// floats returns the values of a repeated float field, packed or not
+func (f protoField) floats() ([]float64, error) {
+	if f.wire == wireFixed32 {
+		return []float64{float64(math.Float32frombits(uint32(f.v)))}, nil
+	}
+	if f.wire != wireBytes || len(f.b)%4 != 0 {
+		return nil, ErrProtobuf
+	}
+	values := make([]float64, len(f.b)/4)
+	for k := range values {
+		values[k] = float64(math.Float32frombits(binary.LittleEndian.Uint32(f.b[4*k:])))
+	}
+	return values, nil
+}
+
+// doubles returns the values of a repeated double field, packed or not
+func (f protoField) doubles() ([]float64, error) {
+	if f.wire == wireFixed64 {
+		return []float64{math.Float64frombits(f.v)}, nil
+	}
+	if f.wire != wireBytes || len(f.b)%8 != 0 {
+		return nil, ErrProtobuf
+	}
+	values := make([]float64, len(f.b)/8)
+	for k := range values {
+		values[k] = math.Float64frombits(binary.LittleEndian.Uint64(f.b[8*k:]))
+	}
+	return values, nil
+}
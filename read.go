@@ -0,0 +1,36 @@
+package neuro
+
+import "io"
+
+const (
+	// maxReadValues is the largest number of values read from a model or data file
+	maxReadValues = 1 << 28
+	// readChunk is the number of values read at once
+	readChunk = 4096
+)
+
+// readChunked reads count values of size bytes and decodes them one by one.
+// The values are read in chunks, so a truncated file fails before the memory
+// of all the values is allocated
+func readChunked(r io.Reader, count, size int, decode func([]byte) float64) ([]float64, error) {
+	chunkSize := readChunk
+	if count < chunkSize {
+		chunkSize = count
+	}
+	values := make([]float64, 0, chunkSize)
+	buf := make([]byte, chunkSize*size)
+	for len(values) < count {
+		left := count - len(values)
+		if left > chunkSize {
+			left = chunkSize
+		}
+		chunk := buf[:left*size]
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, err
+		}
+		for k := 0; k < len(chunk); k += size {
+			values = append(values, decode(chunk[k:k+size]))
+		}
+	}
+	return values, nil
+}
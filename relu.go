@@ -0,0 +1,59 @@
+package neuro
+
+import (
+	"github.com/gonum/matrix/mat64"
+)
+
+type reluFunc struct{}
+
+func init() {
+	activationMap["relu"] = &reluFunc{}
+	activationRowMap["relu"] = reluActivateRow
+	activation32Map["relu"] = reluActivate32
+	onnxActivationMap["relu"] = reluONNX
+}
+
+func (reluFunc) activate(in, out *mat64.Dense, deriv bool, transpose bool) error {
+	return calcActivate(in, out, reluActivate, reluDerivative, deriv, transpose)
+}
+
+func reluActivate(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	return v
+}
+
+// The derivative is taken from the activated value, which is positive only for positive inputs
+func reluDerivative(v float64) float64 {
+	if v > 0 {
+		return 1
+	}
+	return 0
+}
+
+func reluActivateRow(a []float64) { activateFloat(a, reluActivate) }
+
+func reluActivate32(a []float32) {
+	for k, v := range a {
+		if v < 0 {
+			a[k] = 0
+		}
+	}
+}
+
+func reluONNX(b *onnxBuilder, n *Network, layer int, in string) string {
+	return b.node("Relu", in)
+}
+
+func (f reluFunc) backpropError(n *Network, layer int) error {
+	return n.logisticBackprop(f.activate, layer)
+}
+
+// Returns the name of the cost function used by layerError
+func (reluFunc) loss() string { return "mse" }
+
+// Returns the average error on the output layer
+func (f reluFunc) layerError(output *mat64.Dense, target [][]float64) (float64, error) {
+	return meanSquaredError(output, target)
+}
@@ -10,6 +10,9 @@ type sigmoidFunc struct{}
 
 func init() {
 	activationMap["sigmoid"] = &sigmoidFunc{}
+	activationRowMap["sigmoid"] = sigmoidActivateRow
+	activation32Map["sigmoid"] = sigmoidActivate32
+	onnxActivationMap["sigmoid"] = sigmoidONNX
 }
 
 func (sigmoidFunc) activate(in, out *mat64.Dense, deriv bool, transpose bool) error {
@@ -19,10 +22,25 @@ func (sigmoidFunc) activate(in, out *mat64.Dense, deriv bool, transpose bool) er
 func sigmoidActivate(v float64) float64   { return 1.0 / (1.0 + math.Exp(-v)) }
 func sigmoidDerivative(v float64) float64 { return v * (1 - v) }
 
+func sigmoidActivateRow(a []float64) { activateFloat(a, sigmoidActivate) }
+
+func sigmoidActivate32(a []float32) {
+	for k := range a {
+		a[k] = float32(sigmoidActivate(float64(a[k])))
+	}
+}
+
+func sigmoidONNX(b *onnxBuilder, n *Network, layer int, in string) string {
+	return b.node("Sigmoid", in)
+}
+
 func (f sigmoidFunc) backpropError(n *Network, layer int) error {
 	return n.logisticBackprop(f.activate, layer)
 }
 
+// Returns the name of the cost function used by layerError
+func (sigmoidFunc) loss() string { return "mse" }
+
 // Returns the average error on the output layer
 func (f sigmoidFunc) layerError(output *mat64.Dense, target [][]float64) (float64, error) {
 	return meanSquaredError(output, target)
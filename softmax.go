@@ -1,69 +1,151 @@
 package neuro
 
 import (
-	"errors"
-	"fmt"
 	"math"
 
 	"github.com/gonum/matrix/mat64"
 )
 
-type softmaxFunc struct{}
+// softmaxFunc normalizes every group of consecutive nodes of a layer on its
+// own, groups holds the group sizes and nil normalizes the whole layer
+type softmaxFunc struct {
+	groups []int
+}
 
 func init() {
 	activationMap["softmax"] = &softmaxFunc{}
+	onnxActivationMap["softmax"] = softmaxONNX
 }
 
-func (softmaxFunc) activate(in, out *mat64.Dense, deriv bool, transpose bool) error {
+func (sf softmaxFunc) activate(in, out *mat64.Dense, deriv bool, transpose bool) error {
+	f := softmaxActivate
+	if deriv {
+		f = softmaxDerivative
+	}
 	rowsIn, colsIn := in.Dims()
 	rowsOut, colsOut := out.Dims()
 	if transpose {
 		if rowsIn != colsOut || colsIn != rowsOut {
-			return errors.New(fmt.Sprint(ERROR_DIMENSIONS_MISMATCH, trace()))
-		}
-		if deriv {
-			for i := 0; i < rowsIn; i++ {
-				out.SetCol(i, activateSoftmaxFloat(mat64.Row(nil, i, in), softmaxDerivative))
-			}
-			return nil
+			return &ShapeError{Layer: -1, Want: []int{colsIn, rowsIn}, Got: []int{rowsOut, colsOut}}
 		}
+		raw := out.RawMatrix()
 		for i := 0; i < rowsIn; i++ {
-			out.SetCol(i, activateSoftmaxFloat(mat64.Row(nil, i, in), softmaxActivate))
+			activateSoftmaxFloat(in.RawRowView(i), raw.Data[i:], raw.Stride, sf.groups, f)
 		}
 		return nil
 	}
 	if rowsIn != rowsOut || colsIn != colsOut {
-		return errors.New(fmt.Sprint(ERROR_DIMENSIONS_MISMATCH, trace()))
-	}
-	if deriv {
-		for i := 0; i < rowsIn; i++ {
-			out.SetRow(i, activateSoftmaxFloat(mat64.Row(nil, i, in), softmaxDerivative))
-		}
-		return nil
+		return &ShapeError{Layer: -1, Want: []int{rowsIn, colsIn}, Got: []int{rowsOut, colsOut}}
 	}
 	for i := 0; i < rowsIn; i++ {
-		out.SetRow(i, activateSoftmaxFloat(mat64.Row(nil, i, in), softmaxActivate))
+		activateSoftmaxFloat(in.RawRowView(i), out.RawRowView(i), 1, sf.groups, f)
 	}
 	return nil
 }
 
-func activateSoftmaxFloat(a []float64, f func(float64) float64) []float64 {
+// activateSoftmaxFloat applies f to every value of in and normalizes every softmax group.
+// The k-th result is stored in out[k*stride], so out can be a matrice column or in itself
+func activateSoftmaxFloat(in, out []float64, stride int, groups []int, f func(float64) float64) {
 	var sum float64
-	var s, e int
-	step := len(a) / splitSoftmax
-	for i := 0; i < step; i++ {
-		s = i * splitSoftmax
-		e = s + splitSoftmax
+	for s, e := 0, 0; s < len(in); s = e {
+		e = len(in)
+		if groups != nil {
+			e = s + groups[0]
+			groups = groups[1:]
+		}
+		sum = 0
+		for k := s; k < e; k++ {
+			out[k*stride] = f(in[k])
+			sum += out[k*stride]
+		}
+		for k := s; k < e; k++ {
+			out[k*stride] = out[k*stride] / sum
+		}
+	}
+}
+
+// activateRow is the row kernel of the compiled engine
+func (sf softmaxFunc) activateRow(a []float64) {
+	activateSoftmaxFloat(a, a, 1, sf.groups, softmaxActivate)
+}
+
+// activate32 is the row kernel of the float32 network
+func (sf softmaxFunc) activate32(a []float32) {
+	var sum float32
+	groups := sf.groups
+	for s, e := 0, 0; s < len(a); s = e {
+		e = len(a)
+		if groups != nil {
+			e = s + groups[0]
+			groups = groups[1:]
+		}
 		sum = 0
 		for k := range a[s:e] {
-			a[s+k] = f(a[s+k])
+			a[s+k] = float32(softmaxActivate(float64(a[s+k])))
 			sum += a[s+k]
 		}
 		for k := range a[s:e] {
 			a[s+k] = a[s+k] / sum
 		}
 	}
-	return a
+}
+
+// softmaxGroups returns the group sizes of a softmax layer, nil when the whole
+// layer is a single group. SoftmaxGroups takes precedence over SplitSoftmax
+func softmaxGroups(data NetData, layer, nodes int) ([]int, error) {
+	var groups []int
+	switch {
+	case len(data.SoftmaxGroups) > layer && len(data.SoftmaxGroups[layer]) > 0:
+		groups = append(groups, data.SoftmaxGroups[layer]...)
+	case data.SplitSoftmax > 0 && data.SplitSoftmax < nodes:
+		if nodes%data.SplitSoftmax != 0 {
+			return nil, ErrSoftmaxGroups
+		}
+		for k := 0; k < nodes/data.SplitSoftmax; k++ {
+			groups = append(groups, data.SplitSoftmax)
+		}
+	}
+	sum := 0
+	for _, g := range groups {
+		if g < 1 {
+			return nil, ErrSoftmaxGroups
+		}
+		sum += g
+	}
+	if len(groups) > 0 && sum != nodes {
+		return nil, ErrSoftmaxGroups
+	}
+	if len(groups) == 1 {
+		return nil, nil
+	}
+	return groups, nil
+}
+
+// softmaxONNX normalizes every group of the layer. Groups of the same size are
+// reshaped to [batch, groups, size], uneven groups are split and concatenated
+func softmaxONNX(b *onnxBuilder, n *Network, layer int, in string) string {
+	axis := []onnxAttribute{{Name: "axis", Type: onnxAttrInt, I: -1}}
+	groups := n.SoftmaxGroups[layer]
+	if groups == nil {
+		return b.nodeAttr("Softmax", axis, in)
+	}
+	nodes := int64(n.Layers[layer].NodesCount)
+	sizes := make([]int64, len(groups))
+	even := true
+	for k, g := range groups {
+		sizes[k] = int64(g)
+		even = even && g == groups[0]
+	}
+	if even {
+		grouped := b.node("Reshape", in, b.shape(b.prefix+".groups", 0, int64(len(groups)), sizes[0]))
+		return b.node("Reshape", b.nodeAttr("Softmax", axis, grouped), b.shape(b.prefix+".nodes", 0, nodes))
+	}
+	split := b.nodeOutputs("Split", []onnxAttribute{{Name: "axis", Type: onnxAttrInt, I: 1}}, len(groups), in, b.shape(b.prefix+".split", sizes...))
+	activated := make([]string, len(split))
+	for k := range split {
+		activated[k] = b.nodeAttr("Softmax", axis, split[k])
+	}
+	return b.nodeAttr("Concat", []onnxAttribute{{Name: "axis", Type: onnxAttrInt, I: 1}}, activated...)
 }
 
 func softmaxActivate(v float64) float64 {
@@ -77,20 +159,10 @@ func (f softmaxFunc) backpropError(n *Network, layer int) error {
 	return n.logisticBackprop(f.activate, layer)
 }
 
+// Returns the name of the cost function used by layerError
+func (softmaxFunc) loss() string { return "cross-entropy" }
+
 // Returns the average error on the output layer
 func (f softmaxFunc) layerError(output *mat64.Dense, target [][]float64) (float64, error) {
-	r, _ := output.Dims()
-	if len(target) != r {
-		return 0, errors.New(ERROR_DIMENSIONS_MISMATCH)
-	}
-	netError := 0.0
-	for i := 0; i < r; i++ {
-		for k, v := range output.RawRowView(i) {
-			if target[i][k] == 1.0 {
-				netError -= math.Log(v)
-			}
-		}
-	}
-	netError = netError / float64(r)
-	return netError, nil
+	return crossEntropyError(output, target)
 }
@@ -0,0 +1,131 @@
+package neuro
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// softmaxData is a network with a softmax output layer of 10 nodes
+var softmaxData = NetData{
+	Nodes:       []int{3, 6, 10},
+	Activations: []string{"sigmoid", "softmax"},
+	BatchSize:   2,
+	Seed:        3,
+}
+
+// groupSums returns the sum of every group of the output rows
+func groupSums(n *Network, groups []int) [][]float64 {
+	var sums [][]float64
+	for _, row := range n.GetOutput() {
+		var rowSums []float64
+		start := 0
+		for _, g := range groups {
+			var sum float64
+			for _, v := range row[start : start+g] {
+				sum += v
+			}
+			rowSums = append(rowSums, sum)
+			start += g
+		}
+		sums = append(sums, rowSums)
+	}
+	return sums
+}
+
+func TestSoftmaxGroups(t *testing.T) {
+	in := [][]float64{{0.5, -1, 2}, {1, 1, -3}}
+	cases := []struct {
+		split  int
+		groups []int
+		want   []int
+	}{
+		{0, nil, []int{10}},
+		{5, nil, []int{5, 5}},
+		{2, []int{3, 5, 2}, []int{3, 5, 2}},
+	}
+	// All the networks live in the same process and must not share their groups
+	nets := make([]*Network, len(cases))
+	for k, c := range cases {
+		data := softmaxData
+		data.SplitSoftmax = c.split
+		data.SoftmaxGroups = [][]int{nil, c.groups}
+		nets[k] = testNetwork(t, data)
+	}
+	for k, c := range cases {
+		if err := nets[k].Forward(in); err != nil {
+			t.Fatal(err)
+		}
+		for _, sums := range groupSums(nets[k], c.want) {
+			for _, sum := range sums {
+				if math.Abs(sum-1) > 1e-12 {
+					t.Errorf("Groups %v: group sums %v, want 1", c.want, sums)
+				}
+			}
+		}
+	}
+}
+
+func TestSoftmaxGroupsErrors(t *testing.T) {
+	for _, data := range []NetData{
+		{Nodes: []int{2, 10}, Activations: []string{"softmax"}, BatchSize: 1, SplitSoftmax: 3},
+		{Nodes: []int{2, 10}, Activations: []string{"softmax"}, BatchSize: 1, SoftmaxGroups: [][]int{{3, 5}}},
+		{Nodes: []int{2, 10}, Activations: []string{"softmax"}, BatchSize: 1, SoftmaxGroups: [][]int{{10, 0}}},
+		{Nodes: []int{2, 10}, Activations: []string{"sigmoid"}, BatchSize: 1, SoftmaxGroups: [][]int{{5, 5}}},
+		{Nodes: []int{2, 4, 10}, Activations: []string{"sigmoid", "softmax"}, BatchSize: 1, SoftmaxGroups: [][]int{{5, 5}}},
+	} {
+		if _, err := New(data); err == nil {
+			t.Errorf("Expected an error for %v %v", data.SplitSoftmax, data.SoftmaxGroups)
+		}
+	}
+}
+
+func TestSoftmaxGroupsModel(t *testing.T) {
+	in := [][]float64{{0.5, -1, 2}, {1, 1, -3}}
+	data := softmaxData
+	data.SoftmaxGroups = [][]int{nil, {3, 5, 2}}
+	n := testNetwork(t, data)
+	for _, format := range []string{"json", "binary"} {
+		n.Format = format
+		buf := &bytes.Buffer{}
+		if err := n.Encode(buf); err != nil {
+			t.Fatal(err)
+		}
+		y, err := Decode(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(y.SoftmaxGroups) != fmt.Sprint(n.SoftmaxGroups) {
+			t.Errorf("%s: softmax groups %v, want %v", format, y.SoftmaxGroups, n.SoftmaxGroups)
+		}
+		// The other inference engines use the same groups
+		if err := n.Forward(in); err != nil {
+			t.Fatal(err)
+		}
+		c, err := y.Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.Predict(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n32, err := y.Float32()
+		if err != nil {
+			t.Fatal(err)
+		}
+		in32 := [][]float32{{0.5, -1, 2}, {1, 1, -3}}
+		if err := n32.Forward(in32); err != nil {
+			t.Fatal(err)
+		}
+		got32 := n32.GetOutput()
+		for k, row := range n.GetOutput() {
+			for i, v := range row {
+				if math.Abs(got[k][i]-v) > 1e-12 || math.Abs(float64(got32[k][i])-v) > 1e-5 {
+					t.Errorf("%s: output [%d][%d]: compiled %v, float32 %v, want %v", format, k, i, got[k][i], got32[k][i], v)
+				}
+			}
+		}
+	}
+}
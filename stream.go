@@ -0,0 +1,158 @@
+package neuro
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"time"
+)
+
+type (
+	// StreamOptions configures a Stream
+	StreamOptions struct {
+		// BatchSize is the number of samples of every batch
+		BatchSize int
+		// Window is the number of samples buffered to shuffle them, 0 or 1 keeps the order of the source
+		Window int
+		// Rand shuffles the window, a generator seeded with the time when nil
+		Rand *rand.Rand
+	}
+	// Stream reads the samples of a source lazily and returns them in batches.
+	// Only the shuffle window is held in memory, so the source can be larger than it
+	Stream struct {
+		opts   StreamOptions
+		open   func() (io.ReadCloser, error)
+		decode func(io.Reader) (SampleReader, error)
+		source io.ReadCloser
+		reader SampleReader
+		window []streamSample
+		eof    bool
+	}
+	streamSample struct {
+		in     []float64
+		target []float64
+	}
+)
+
+// NewStream returns a stream of the samples decoded from the sources returned
+// by open, which is called again by Rewind for every epoch
+func NewStream(open func() (io.ReadCloser, error), decode func(io.Reader) (SampleReader, error), opts StreamOptions) (*Stream, error) {
+	if opts.BatchSize < 1 {
+		return nil, ErrBatchSize
+	}
+	if opts.Rand == nil {
+		opts.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	s := &Stream{opts: opts, open: open, decode: decode}
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// OpenStream returns a stream of the samples of a file, Rewind reopens the file
+func OpenStream(path string, decode func(io.Reader) (SampleReader, error), opts StreamOptions) (*Stream, error) {
+	return NewStream(func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}, decode, opts)
+}
+
+// ReaderStream returns a stream of the samples of r, which is not closed.
+// Rewind seeks back to the start of r and returns ErrRewind if r can not seek
+func ReaderStream(r io.Reader, decode func(io.Reader) (SampleReader, error), opts StreamOptions) (*Stream, error) {
+	opened := false
+	return NewStream(func() (io.ReadCloser, error) {
+		if opened {
+			seeker, ok := r.(io.Seeker)
+			if !ok {
+				return nil, ErrRewind
+			}
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		opened = true
+		return ioutil.NopCloser(r), nil
+	}, decode, opts)
+}
+
+// start opens the source for a new epoch
+func (s *Stream) start() error {
+	source, err := s.open()
+	if err != nil {
+		return err
+	}
+	reader, err := s.decode(source)
+	if err != nil {
+		source.Close()
+		return err
+	}
+	s.source, s.reader = source, reader
+	s.window = s.window[:0]
+	s.eof = false
+	return nil
+}
+
+// Next returns the next batch of inputs and targets. The last batch of an
+// epoch can be shorter, after it Next returns io.EOF until Rewind
+func (s *Stream) Next() ([][]float64, [][]float64, error) {
+	in := make([][]float64, 0, s.opts.BatchSize)
+	target := make([][]float64, 0, s.opts.BatchSize)
+	for len(in) < s.opts.BatchSize {
+		sample, err := s.sample()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		in = append(in, sample.in)
+		target = append(target, sample.target)
+	}
+	if len(in) == 0 {
+		return nil, nil, io.EOF
+	}
+	return in, target, nil
+}
+
+// sample fills the window from the source and takes a random sample out of it
+func (s *Stream) sample() (streamSample, error) {
+	for !s.eof && (len(s.window) == 0 || len(s.window) < s.opts.Window) {
+		in, target, err := s.reader.Read()
+		if err == io.EOF {
+			s.eof = true
+			break
+		}
+		if err != nil {
+			return streamSample{}, err
+		}
+		s.window = append(s.window, streamSample{in, target})
+	}
+	if len(s.window) == 0 {
+		return streamSample{}, io.EOF
+	}
+	k := 0
+	if s.opts.Window > 1 {
+		k = s.opts.Rand.Intn(len(s.window))
+	}
+	sample := s.window[k]
+	// Without shuffling the window holds a single sample
+	last := len(s.window) - 1
+	s.window[k] = s.window[last]
+	s.window = s.window[:last]
+	return sample, nil
+}
+
+// Rewind starts a new epoch from the start of the source
+func (s *Stream) Rewind() error {
+	if err := s.source.Close(); err != nil {
+		return err
+	}
+	return s.start()
+}
+
+// Close closes the source
+func (s *Stream) Close() error {
+	return s.source.Close()
+}
@@ -0,0 +1,129 @@
+package neuro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// streamCSV returns count records whose input is their index and target twice the index
+func streamCSV(count int) string {
+	buf := &bytes.Buffer{}
+	for k := 0; k < count; k++ {
+		fmt.Fprintf(buf, "%d,%d\n", k, 2*k)
+	}
+	return buf.String()
+}
+
+// streamEpoch reads all the batches of an epoch and returns the inputs and the batch sizes
+func streamEpoch(t *testing.T, s *Stream) ([]float64, []int) {
+	var (
+		inputs []float64
+		sizes  []int
+	)
+	for {
+		in, target, err := s.Next()
+		if err == io.EOF {
+			return inputs, sizes
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k := range in {
+			if target[k][0] != 2*in[k][0] {
+				t.Fatalf("Sample %v has the target %v", in[k], target[k])
+			}
+			inputs = append(inputs, in[k][0])
+		}
+		sizes = append(sizes, len(in))
+	}
+}
+
+func TestStream(t *testing.T) {
+	opts := CSVOptions{Targets: []string{"1"}}
+	s, err := ReaderStream(strings.NewReader(streamCSV(10)), CSVDecoder(opts), StreamOptions{BatchSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for epoch := 0; epoch < 2; epoch++ {
+		inputs, sizes := streamEpoch(t, s)
+		if fmt.Sprint(sizes) != "[4 4 2]" || fmt.Sprint(inputs) != "[0 1 2 3 4 5 6 7 8 9]" {
+			t.Errorf("Epoch %d: batches %v of %v", epoch, sizes, inputs)
+		}
+		if err := s.Rewind(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err = ReaderStream(strings.NewReader(streamCSV(50)), CSVDecoder(opts), StreamOptions{BatchSize: 8, Window: 10, Rand: rand.New(rand.NewSource(1))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs, _ := streamEpoch(t, s)
+	seen := map[float64]bool{}
+	ordered := true
+	for k, v := range inputs {
+		seen[v] = true
+		ordered = ordered && v == float64(k)
+	}
+	if len(inputs) != 50 || len(seen) != 50 || ordered {
+		t.Errorf("Expected the 50 samples shuffled, got %v", inputs)
+	}
+
+	s, err = ReaderStream(io.MultiReader(strings.NewReader(streamCSV(3))), CSVDecoder(opts), StreamOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rewind(); !errors.Is(err, ErrRewind) {
+		t.Errorf("Expected ErrRewind, got %v", err)
+	}
+}
+
+func TestStreamFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.svm")
+	if err := ioutil.WriteFile(path, []byte("1 1:0.5\n0 2:1\n1 1:1 2:1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenStream(path, LIBSVMDecoder(LIBSVMOptions{OneHot: true}), StreamOptions{BatchSize: 2}); !errors.Is(err, ErrDataset) {
+		t.Errorf("Expected ErrDataset without the features, got %v", err)
+	}
+	s, err := OpenStream(path, LIBSVMDecoder(LIBSVMOptions{Features: 2, OneHot: true, Classes: []float64{0, 1}}), StreamOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	n, err := New(NetData{Nodes: []int{2, 3, 2}, Activations: []string{"tanh", "softmax"}, BatchSize: 2, Train: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.LearnRate = 0.1
+	for epoch := 0; epoch < 3; epoch++ {
+		for {
+			in, target, err := s.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := n.Forward(in); err != nil {
+				t.Fatal(err)
+			}
+			if err := n.Backward(target); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Rewind(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n.Step != 6 {
+		t.Errorf("Expected 6 training steps, got %d", n.Step)
+	}
+}
@@ -1,8 +1,6 @@
 package neuro
 
 import (
-	"math"
-
 	"github.com/gonum/matrix/mat64"
 )
 
@@ -10,6 +8,9 @@ type tanhFunc struct{}
 
 func init() {
 	activationMap["tanh"] = &tanhFunc{}
+	activationRowMap["tanh"] = tanhActivateRow
+	activation32Map["tanh"] = tanhActivate32
+	onnxActivationMap["tanh"] = tanhONNX
 }
 
 func (tanhFunc) activate(in, out *mat64.Dense, deriv bool, transpose bool) error {
@@ -25,17 +26,46 @@ func tanhActivate(v float64) float64 {
 	if v > 3 {
 		return 1
 	}
-	sq := math.Pow(v, 2)
+	sq := v * v
 	return v * (27 + sq) / (27 + 9*sq)
 }
+func tanhActivateRow(a []float64) { activateFloat(a, tanhActivate) }
+
+func tanhActivate32(a []float32) {
+	for k, v := range a {
+		switch {
+		case v < -3:
+			a[k] = -1
+		case v > 3:
+			a[k] = 1
+		default:
+			sq := v * v
+			a[k] = v * (27 + sq) / (27 + 9*sq)
+		}
+	}
+}
+
+// tanhONNX exports the same approximation as tanhActivate instead of the
+// exact Tanh operator, so the exported graph gives the network's outputs
+func tanhONNX(b *onnxBuilder, n *Network, layer int, in string) string {
+	v := b.node("Clip", in, b.constant(-3), b.constant(3))
+	sq := b.node("Mul", v, v)
+	num := b.node("Mul", v, b.node("Add", sq, b.constant(27)))
+	den := b.node("Add", b.node("Mul", sq, b.constant(9)), b.constant(27))
+	return b.node("Div", num, den)
+}
+
 func tanhDerivative(v float64) float64 {
-	return 1 - math.Pow(v, 2)
+	return 1 - v*v
 }
 
 func (f tanhFunc) backpropError(n *Network, layer int) error {
 	return n.logisticBackprop(f.activate, layer)
 }
 
+// Returns the name of the cost function used by layerError
+func (tanhFunc) loss() string { return "mse" }
+
 // Returns the average error on the output layer
 func (f tanhFunc) layerError(output *mat64.Dense, target [][]float64) (float64, error) {
 	return meanSquaredError(output, target)
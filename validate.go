@@ -0,0 +1,57 @@
+package neuro
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// checkRows checks that there are at most batchSize rows of width finite values.
+// Rows of another width give a ShapeError of the layer matching err
+func checkRows(rows [][]float64, batchSize, width, layer int, err error) error {
+	if len(rows) > batchSize {
+		return fmt.Errorf("%w: got %d rows, batch size %d", ErrWrongBatchCount, len(rows), batchSize)
+	}
+	for r, row := range rows {
+		if len(row) != width {
+			return &ShapeError{Err: err, Layer: layer, Want: []int{len(rows), width}, Got: []int{len(rows), len(row)}}
+		}
+		for c, v := range row {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return &ValueError{Layer: -1, Row: r, Col: c, Value: v}
+			}
+		}
+	}
+	return nil
+}
+
+// checkRows32 is checkRows for the float32 inputs of Network32
+func checkRows32(rows [][]float32, batchSize, width int) error {
+	if len(rows) > batchSize {
+		return fmt.Errorf("%w: got %d rows, batch size %d", ErrWrongBatchCount, len(rows), batchSize)
+	}
+	for r, row := range rows {
+		if len(row) != width {
+			return &ShapeError{Err: ErrWrongInputsCount, Layer: -1, Want: []int{len(rows), width}, Got: []int{len(rows), len(row)}}
+		}
+		for c, v := range row {
+			if v != v || math.IsInf(float64(v), 0) {
+				return &ValueError{Layer: -1, Row: r, Col: c, Value: float64(v)}
+			}
+		}
+	}
+	return nil
+}
+
+// checkLayer checks the first rows of a layer's output for NaN and infinite values
+func checkLayer(m *mat64.Dense, rows, layer int) error {
+	for r := 0; r < rows; r++ {
+		for c, v := range m.RawRowView(r) {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return &ValueError{Layer: layer, Row: r, Col: c, Value: v}
+			}
+		}
+	}
+	return nil
+}